@@ -11,9 +11,12 @@ type Singleton struct {
 	NamedOnly       bool   `value:"false"`
 	UseConstructor  bool   `value:"false"`
 	LocalGetter     bool   `value:"false"`
+	Primary         bool   `value:"false"`
 	LocalPrefix     string `value:"__"`
 	LocalGetterName string
 	InitMethod      string
+	DestroyMethod   string
+	Profile         string
 	Init            []string
 	typeName        string
 }
@@ -44,6 +47,20 @@ func (s *Singleton) WriteString(buf io.StringWriter) error {
 		}
 	}
 
+	var profiles []string
+	for _, v := range strings.Split(s.Profile, ",") {
+		if v = strings.TrimSpace(v); len(v) > 0 {
+			profiles = append(profiles, fmt.Sprintf(`"%s"`, v))
+		}
+	}
+	if len(profiles) > 0 {
+		buf.WriteString(fmt.Sprintf(`.Profile(%s)`, strings.Join(profiles, ", ")))
+	}
+
+	if s.Primary {
+		buf.WriteString(".Primary()")
+	}
+
 	if s.UseConstructor {
 		buf.WriteString(".UseConstructor(true)")
 	}
@@ -52,6 +69,10 @@ func (s *Singleton) WriteString(buf io.StringWriter) error {
 		buf.WriteString(fmt.Sprintf(`.InitMethodName("%s")`, s.InitMethod))
 	}
 
+	if len(s.DestroyMethod) > 0 {
+		buf.WriteString(fmt.Sprintf(`.DestroyMethodName("%s")`, s.DestroyMethod))
+	}
+
 	if len(s.Init) > 0 {
 		var quoted []string
 
@@ -19,20 +19,60 @@ type Tag string
 
 // WireValue is an enum
 // @EnumConfig(marshal, values, noComments, noCase)
-// @Enum{self, auto, type, name, value}
+// @Enum{self, auto, type, name, value, all, map}
 type WireValue string
 
+// OptionalModifier is appended to a tag value to leave the field as it is when nothing is registered for it,
+// such as `wire:"auto,optional"` or `value:"${metrics.port},optional"`. A value ending with the literal text ",optional"
+// escapes its comma, such as `value:"a\\,optional"` for "a,optional".
+const OptionalModifier = "optional"
+
 type TagWithValue struct {
-	Tag   WireValue
-	Value string
+	Tag      WireValue
+	Value    string
+	Optional bool
+	Layout   string // the layout of a time.Time 'value' field, see LayoutTagName
 }
 
 func (tv *TagWithValue) String() string {
 	return fmt.Sprintf("%v:%s", tv.Tag, tv.Value)
 }
 
+// cutOptional removes the optional modifier from the end of tagValue, and unescapes the literal ",optional" before it.
+func cutOptional(tagValue string) (string, bool) {
+	tagValue = strings.TrimSpace(tagValue)
+
+	optional := false
+	if i, escaped := lastOptional(tagValue); i >= 0 && !escaped {
+		tagValue, optional = strings.TrimSpace(tagValue[:i]), true
+	}
+
+	if i, escaped := lastOptional(tagValue); i >= 0 && escaped {
+		tagValue = tagValue[:i-1] + tagValue[i:]
+	}
+
+	return tagValue, optional
+}
+
+// lastOptional returns the index of the comma of the ",optional" ending tagValue, or -1,
+// and whether the comma is escaped by a backslash.
+func lastOptional(tagValue string) (int, bool) {
+	i := strings.LastIndex(tagValue, ",")
+	if i < 0 || strings.TrimSpace(tagValue[i+1:]) != OptionalModifier {
+		return -1, false
+	}
+	return i, i > 0 && tagValue[i-1] == '\\'
+}
+
+// parseValueTag parses the 'value' tag.
+func parseValueTag(tagValue string) *TagWithValue {
+	value, optional := cutOptional(tagValue)
+	return &TagWithValue{Tag: WireValueValue, Value: value, Optional: optional}
+}
+
 func ParseTagValue(tagValue string, checkAndSet func(tv *TagWithValue)) (tv *TagWithValue, err error) {
 	result := &TagWithValue{}
+	tagValue, result.Optional = cutOptional(tagValue)
 
 	var values []string
 	for _, v := range strings.SplitN(strings.TrimSpace(tagValue), ":", 2) {
@@ -70,6 +110,42 @@ func ParseTagValue(tagValue string, checkAndSet func(tv *TagWithValue)) (tv *Tag
 	}
 }
 
+// parseWireTag parses the 'wire' tag of structField, the field name is used when the name is omitted.
+func parseWireTag(wireValue string, structField reflect.StructField) (*TagWithValue, error) {
+	return ParseTagValue(wireValue, func(tv *TagWithValue) {
+		if (tv.Tag == WireValueName && len(tv.Value) == 0) ||
+			(tv.Tag == WireValueAuto) {
+			tv.Value = structField.Name
+		}
+	})
+}
+
+// isNotFound reports whether err is raised because the builder itself isn't registered,
+// not because something it depends on is missing.
+func isNotFound(err error) bool {
+	var re *ResolveError
+	return errors.As(err, &re) && re.Kind == ErrNotFound
+}
+
+// getOptionalValueByWireTag is like getValueByWireTag, but if the tag is optional,
+// found is false instead of an error when nothing is registered.
+// The builders are looked up before getting the object, so the errors of building them are still returned.
+func getOptionalValueByWireTag(ctx context.Context, self any, tagValue *TagWithValue, t reflect.Type) (result any, found bool, err error) {
+	if tagValue.Optional && (tagValue.Tag == WireValueSelf || !isLazyType(t)) {
+		ref := &staticRef{vt: t, tv: tagValue}
+		for local := range exprLocals(ctx, self) {
+			ref.locals = append(ref.locals, local)
+		}
+
+		if _, err = getContextContainer(ctx).resolveRef(ctx, ref); isNotFound(err) {
+			return nil, false, nil
+		}
+	}
+
+	result, err = getValueByWireTag(ctx, self, tagValue, t)
+	return result, err == nil, err
+}
+
 func wireError(structField reflect.StructField, rootValues []reflect.Value, wireRule string) error {
 	fieldPath := structure.GetFieldPath(structField, rootValues)
 	return fmt.Errorf("the field of 'wire' must be defined as a pointer to an object or an interface. %s, tag value: %s", fieldPath, wireRule)
@@ -86,44 +162,60 @@ func getExpr(value string) (exprCode string, isExpr bool) {
 func getValueByWireTag(ctx context.Context, self any, tagValue *TagWithValue, t reflect.Type) (any, error) {
 	switch tagValue.Tag {
 	case WireValueSelf, WireValueAuto, WireValueType, WireValueName:
+		if tagValue.Tag != WireValueSelf && isLazyType(t) {
+			// got on the first call
+			return lazyFunc(ctx, self, tagValue, t).Interface(), nil
+		}
+
 		if (t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct) || t.Kind() == reflect.Interface {
 			switch tagValue.Tag {
 			case WireValueSelf:
 				return self, nil
 			case WireValueAuto:
 				if len(tagValue.Value) > 0 {
-					return _context.getByNameOrType(ctx, tagValue.Value, t), nil
+					return getContextContainer(ctx).getByNameOrType(ctx, tagValue.Value, t), nil
 				} else {
-					return _context.getByType(ctx, t), nil
+					return getContextContainer(ctx).getByType(ctx, t), nil
 				}
 			case WireValueType:
-				return _context.getByType(ctx, t), nil
+				return getContextContainer(ctx).getByType(ctx, t), nil
 			case WireValueName:
 				if len(tagValue.Value) > 0 {
-					return _context.getByNamePanic(ctx, tagValue.Value, t), nil
+					return getContextContainer(ctx).getByNamePanic(ctx, tagValue.Value, t), nil
 				}
 			}
 		} else {
 			return nil, errors.New("‘self’, ’auto’, ’type’ and ’name’ tag only used on *struct or interface")
 		}
+	case WireValueAll:
+		if t.Kind() == reflect.Slice && isWirableType(t.Elem()) {
+			return getContextContainer(ctx).getAll(ctx, t).Interface(), nil
+		} else {
+			return nil, errors.New("'all' tag only used on a slice of *struct or interface")
+		}
+	case WireValueMap:
+		if t.Kind() == reflect.Map && t.Key().Kind() == reflect.String && isWirableType(t.Elem()) {
+			return getContextContainer(ctx).getNamedMap(ctx, t, tagValue.Value).Interface(), nil
+		} else {
+			return nil, errors.New("'map' tag only used on a map of string to *struct or interface")
+		}
 	case WireValueValue:
-		if (t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct) || t.Kind() == reflect.Struct {
-			return nil, errors.New("'value' tag can't used on *struct or struct")
+		if ((t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct) || t.Kind() == reflect.Struct) && !isConvertible(t) {
+			return nil, errors.New("'value' tag can't used on *struct or struct without a converter")
 		}
 
 		if len(tagValue.Value) > 0 {
+			segments, err := parseTemplate(strings.TrimSpace(tagValue.Value))
+			if err != nil {
+				return nil, fmt.Errorf("tag value %s parse err: %w", tagValue, err)
+			}
 
-			exprCode, isExpr := getExpr(tagValue.Value)
-			if isExpr {
-				value, err := _context.evalExpr(ctx, exprCode)
-				if err != nil {
-					return nil, fmt.Errorf("tag value %s expr eval err: %v", tagValue, err)
-				}
-
-				return structure.ConvertToType(value, t)
-			} else {
-				return structure.ConvertToType(exprCode, t)
+			value, err := getContextContainer(ctx).evalTemplate(ctx, exprLocals(ctx, self), segments)
+			if err != nil {
+				return nil, fmt.Errorf("tag value %s expr eval err: %w", tagValue, err)
 			}
+
+			return convertValue(value, t, tagValue.Layout)
 		}
 	}
 
@@ -135,7 +227,7 @@ func AutoWire(self any) error {
 }
 
 func AutoWireTimeout(self any, timeout time.Duration) error {
-	return autoWireContext(getTimeoutContext(timeout), self)
+	return autoWireContext(_context.timeoutContext(timeout), self)
 }
 
 func autoWireContext(ctx context.Context, self any) error {
@@ -143,19 +235,25 @@ func autoWireContext(ctx context.Context, self any) error {
 		return nil
 	}
 
-	return structure.WalkWithTagNames(self, []string{TagWire.Name(), TagValue.Name(), TagNew.Name()}, func(fieldValue reflect.Value, structField reflect.StructField, rootValues []reflect.Value, tags map[string]string) (err error) {
+	ctx, scope := withExprScope(ctx, self)
+
+	return walkTags(self, []string{TagWire.Name(), TagValue.Name(), TagNew.Name()}, func(fieldValue reflect.Value, structField reflect.StructField, rootValues []reflect.Value, tags map[string]string) (err error) {
+		defer func() {
+			if err == nil {
+				// visible to the exprs of the following fields
+				scope.wired(fieldValue, structField, rootValues)
+			}
+		}()
+
 		if len(tags) > 1 {
 			panic("Only one can exist at a time, either 'wire', 'value' or 'new'.")
 		}
 
 		if newValue, ok := tags[TagNew.Name()]; ok {
 			// new， create by factory
-			factoriesLock.RLock()
-			f, loaded := factories[structField.Type]
-			factoriesLock.RUnlock()
-
+			f, loaded := getContextContainer(ctx).getFactory(structField.Type)
 			if !loaded {
-				return fmt.Errorf("can't get factory type of %s", structField.Type.String())
+				return newResolveError(ErrNotFound, nil, "can't get factory type of %s", structField.Type.String())
 			}
 			newValue = strings.TrimSpace(newValue)
 			if len(newValue) > 0 {
@@ -167,15 +265,11 @@ func autoWireContext(ctx context.Context, self any) error {
 
 		var tv *TagWithValue
 		if wireValue, ok := tags[TagWire.Name()]; ok {
-			tv, err = ParseTagValue(wireValue, func(tv *TagWithValue) {
-				if (tv.Tag == WireValueName && len(tv.Value) == 0) ||
-					(tv.Tag == WireValueAuto) {
-					tv.Value = structField.Name
-				}
-			})
+			tv, err = parseWireTag(wireValue, structField)
 		}
 		if wireValue, ok := tags[TagValue.Name()]; ok {
-			tv = &TagWithValue{Tag: WireValueValue, Value: wireValue}
+			tv = parseValueTag(wireValue)
+			tv.Layout = structField.Tag.Get(LayoutTagName)
 		}
 
 		if err != nil {
@@ -183,7 +277,7 @@ func autoWireContext(ctx context.Context, self any) error {
 		}
 
 		switch tv.Tag {
-		case WireValueSelf, WireValueAuto, WireValueType, WireValueName:
+		case WireValueSelf, WireValueAuto, WireValueType, WireValueName, WireValueAll, WireValueMap:
 			if !fieldValue.IsNil() {
 				// field is not nil， skip it
 				return nil
@@ -191,14 +285,19 @@ func autoWireContext(ctx context.Context, self any) error {
 		default:
 		}
 
-		if wiredValue, err1 := getValueByWireTag(ctx, self, tv, structField.Type); err1 == nil {
+		if wiredValue, found, err1 := getOptionalValueByWireTag(ctx, self, tv, structField.Type); err1 == nil {
+			if !found {
+				// optional, nothing registered
+				return nil
+			}
+
 			// Prefer using the set method
 			if structure.SetFieldBySetMethod(fieldValue, wiredValue, structField, rootValues[len(rootValues)-1]) {
 				return nil
 			}
 			return structure.SetField(fieldValue, wiredValue)
 		} else {
-			return fmt.Errorf("%v on %s", err1, structure.GetFieldPath(structField, rootValues))
+			return fmt.Errorf("%w on %s", err1, structure.GetFieldPath(structField, rootValues))
 		}
 	})
 }
@@ -25,6 +25,8 @@ const (
 	WireValueType  WireValue = "type"
 	WireValueName  WireValue = "name"
 	WireValueValue WireValue = "value"
+	WireValueAll   WireValue = "all"
+	WireValueMap   WireValue = "map"
 )
 
 var ErrInvalidTag = errors.New("not a valid Tag")
@@ -76,6 +78,8 @@ var _WireValueNameMap = map[string]WireValue{
 	"type":  WireValueType,
 	"name":  WireValueName,
 	"value": WireValueValue,
+	"all":   WireValueAll,
+	"map":   WireValueMap,
 }
 
 // Name is the attribute of WireValue.
@@ -97,6 +101,8 @@ var _WireValueValues = []WireValue{
 	WireValueType,
 	WireValueName,
 	WireValueValue,
+	WireValueAll,
+	WireValueMap,
 }
 
 // WireValueValues returns a list of the values of WireValue
@@ -1,8 +1,10 @@
 package factory
 
 import (
+	"context"
 	"fmt"
 	"github.com/expr-lang/expr"
+	"github.com/stretchr/testify/assert"
 	"net/url"
 	"reflect"
 	"strings"
@@ -526,3 +528,262 @@ func isNoErr(tb testing.TB, err error) {
 		tb.Fatalf("unexpected error: %v", err)
 	}
 }
+
+type allHandler interface {
+	Handle() string
+}
+
+type allFirst struct{}
+
+func (h *allFirst) Handle() string { return "first" }
+
+type allSecond struct{}
+
+func (h *allSecond) Handle() string { return "second" }
+
+type allThird struct{}
+
+func (h *allThird) Handle() string { return "third" }
+
+type allRegistry struct {
+	Handlers []allHandler  `wire:"all"`
+	Seconds  []*allSecond  `wire:"all"`
+	Empty    []*tryMissing `wire:"all"`
+}
+
+func TestAutoWireAll(t *testing.T) {
+	c := NewContainer()
+	c.Singleton(new(allThird))
+	c.NamedSingleton("b", new(allSecond))
+	c.NamedSingleton("a", new(allSecond))
+	c.Singleton(new(allFirst)).Order(-1)
+	c.Singleton(new(allRegistry))
+
+	var registry *allRegistry
+	c.Find(&registry)
+
+	var handles []string
+	for _, h := range registry.Handlers {
+		handles = append(handles, h.Handle())
+	}
+	assert.Equal(t, []string{"first", "third", "second", "second"}, handles)
+
+	var a, b *allSecond
+	c.FindByName("a", &a)
+	c.FindByName("b", &b)
+	assert.Equal(t, []*allSecond{a, b}, registry.Seconds)
+	assert.Empty(t, registry.Empty)
+
+	assert.Nil(t, c.Validate())
+}
+
+type allScoped struct{}
+
+func (h *allScoped) Handle() string { return "scoped" }
+
+func TestAutoWireAllScoped(t *testing.T) {
+	c := NewContainer()
+	c.Singleton(new(allFirst))
+	c.Scoped(new(allScoped))
+
+	handles := func(registry *allRegistry) (result []string) {
+		for _, h := range registry.Handlers {
+			result = append(result, h.Handle())
+		}
+		return
+	}
+
+	// the scoped ones are skipped without a scope
+	var registry *allRegistry
+	c.New(&registry)
+	assert.Equal(t, []string{"first"}, handles(registry))
+
+	ctx, cancel := WithScope(context.Background())
+	defer cancel()
+
+	c.NewContext(ctx, &registry)
+	assert.Equal(t, []string{"first", "scoped"}, handles(registry))
+}
+
+type allLast interface {
+	allHandler
+}
+
+func TestAutoWireAllOrder(t *testing.T) {
+	c := NewContainer()
+	c.Singleton(new(allFirst))
+	c.Prototype(new(allThird)).Order(-2)
+	c.Scoped(new(allScoped)).Order(-1)
+	c.Interface((*allLast)(nil)).Order(1).SetInitFunc(func() any { return &allSecond{} })
+
+	ctx, cancel := WithScope(context.Background())
+	defer cancel()
+
+	var registry *allRegistry
+	c.NewContext(ctx, &registry)
+
+	var handles []string
+	for _, h := range registry.Handlers {
+		handles = append(handles, h.Handle())
+	}
+	assert.Equal(t, []string{"third", "scoped", "first", "second"}, handles)
+}
+
+type mapCodec interface {
+	Encode() string
+}
+
+type mapJSON struct{}
+
+func (c *mapJSON) Encode() string { return "json" }
+
+type mapXML struct{}
+
+func (c *mapXML) Encode() string { return "xml" }
+
+type mapRegistry struct {
+	Codecs map[string]mapCodec   `wire:"map:codec."`
+	All    map[string]mapCodec   `wire:"map"`
+	Jsons  map[string]*mapJSON   `wire:"map"`
+	Empty  map[string]*tryMemory `wire:"map"`
+}
+
+func TestAutoWireMap(t *testing.T) {
+	c := NewContainer()
+	c.NamedSingleton("codec.json", new(mapJSON))
+	c.NamedSingleton("codec.xml", new(mapXML))
+	c.NamedSingleton("legacy", new(mapXML))
+	c.Singleton(new(mapJSON))
+	c.Singleton(new(mapRegistry))
+
+	var registry *mapRegistry
+	c.Find(&registry)
+
+	assert.Len(t, registry.Codecs, 2)
+	assert.Equal(t, "json", registry.Codecs["codec.json"].Encode())
+	assert.Equal(t, "xml", registry.Codecs["codec.xml"].Encode())
+
+	assert.Len(t, registry.All, 3)
+	assert.Contains(t, registry.All, "legacy")
+
+	var json *mapJSON
+	c.FindByName("codec.json", &json)
+	assert.Equal(t, map[string]*mapJSON{"codec.json": json}, registry.Jsons)
+	assert.Empty(t, registry.Empty)
+
+	assert.Nil(t, c.Validate())
+}
+
+type mapYAML struct{}
+
+func (c *mapYAML) Encode() string { return "yaml" }
+
+type mapScopedRegistry struct {
+	Codecs map[string]mapCodec `wire:"map:codec."`
+}
+
+func TestAutoWireMapScoped(t *testing.T) {
+	c := NewContainer()
+	c.NamedSingleton("codec.json", new(mapJSON))
+	c.NamedScoped("codec.yaml", new(mapYAML))
+
+	// the scoped ones are skipped without a scope
+	var registry *mapScopedRegistry
+	c.New(&registry)
+	assert.Len(t, registry.Codecs, 1)
+
+	ctx, cancel := WithScope(context.Background())
+	defer cancel()
+
+	c.NewContext(ctx, &registry)
+	assert.Len(t, registry.Codecs, 2)
+	assert.Equal(t, "yaml", registry.Codecs["codec.yaml"].Encode())
+}
+
+type optionalMetrics struct{}
+
+type optionalService struct {
+	Metrics *optionalMetrics `wire:"auto,optional"`
+	Cache   *scopedConn      `wire:"name:cache, optional"`
+	Store   tryStorage       `wire:"type,optional"`
+	Port    int              `value:"${metrics.port},optional"`
+	Host    string           `value:"localhost,optional"`
+	Literal string           `value:"a\\,optional"`
+
+	initMetrics *optionalMetrics
+}
+
+func (s *optionalService) Init(metrics *optionalMetrics) {
+	s.initMetrics = metrics
+}
+
+type optionalBroken struct {
+	Broken *tryBroken `wire:"auto,optional"`
+}
+
+type optionalAmbiguous struct {
+	Store tryStorage `wire:"type,optional"`
+}
+
+type optionalInitDep struct{}
+
+func (d *optionalInitDep) Init(missing *tryMissing) {}
+
+type optionalFieldDep struct {
+	Missing *tryMissing `wire:"auto"`
+}
+
+type optionalDeps struct {
+	Init  *optionalInitDep  `wire:"auto,optional"`
+	Field *optionalFieldDep `wire:"auto,optional"`
+}
+
+func TestAutoWireOptional(t *testing.T) {
+	tv, err := ParseTagValue("name:cache, optional", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, &TagWithValue{Tag: WireValueName, Value: "cache", Optional: true}, tv)
+
+	// the escaped literal
+	assert.Equal(t, &TagWithValue{Tag: WireValueValue, Value: "a,optional"}, parseValueTag(`a\,optional`))
+	assert.Equal(t, &TagWithValue{Tag: WireValueValue, Value: "a,optional", Optional: true}, parseValueTag(`a\,optional,optional`))
+
+	c := NewContainer()
+	c.Singleton(new(optionalService)).InitParams("auto,optional")
+
+	var service *optionalService
+	c.Find(&service)
+	assert.Nil(t, service.Metrics)
+	assert.Nil(t, service.Cache)
+	assert.Nil(t, service.Store)
+	assert.Equal(t, 0, service.Port)
+	assert.Equal(t, "localhost", service.Host)
+	assert.Equal(t, "a,optional", service.Literal)
+	assert.Nil(t, service.initMetrics)
+	assert.Nil(t, c.Validate())
+
+	c.Singleton(new(optionalMetrics))
+	var metrics *optionalMetrics
+	c.Find(&metrics)
+	var service2 *optionalService
+	c.New(&service2)
+	assert.Same(t, metrics, service2.Metrics)
+
+	c.Singleton(new(tryBroken))
+	var broken *optionalBroken
+	assert.ErrorIs(t, c.TryNew(&broken), ErrInitFailed)
+
+	c.Singleton(new(tryDisk))
+	c.Singleton(new(tryMemory))
+	var ambiguous *optionalAmbiguous
+	assert.ErrorIs(t, c.TryNew(&ambiguous), ErrAmbiguous)
+
+	// registered, but what they depend on is missing
+	var deps *optionalDeps
+	c1 := NewContainer()
+	c1.Singleton(new(optionalInitDep))
+	assert.ErrorIs(t, c1.TryNew(&deps), ErrNotFound)
+
+	c2 := NewContainer()
+	c2.Singleton(new(optionalFieldDep))
+	assert.ErrorIs(t, c2.TryNew(&deps), ErrNotFound)
+}
@@ -0,0 +1,154 @@
+package factory
+
+import (
+	"context"
+	"fmt"
+	"github.com/expgo/sync"
+	"reflect"
+)
+
+// Condition decides whether a registration takes effect, c is the container the registration belongs to.
+// The objects it gets must be got with ctx, which holds the registrations being evaluated.
+type Condition func(ctx context.Context, c *Container) bool
+
+// When returns a Condition which holds when the expr code, such as "${env.CACHE == 'redis'}", is evaluated to true.
+func When(code string) Condition {
+	exprCode, _ := getExpr(code)
+
+	return func(ctx context.Context, c *Container) bool {
+		value, err := c.context.evalExpr(initTypeCtx(withContainer(ctx, c.context)), nil, exprCode)
+		if err != nil {
+			panic(fmt.Errorf("condition %s eval err: %w", code, err))
+		}
+
+		result, ok := value.(bool)
+		if !ok {
+			panic(fmt.Errorf("condition %s must be evaluated to a bool, got %v", code, value))
+		}
+
+		return result
+	}
+}
+
+// OnMissing returns a Condition which holds when T, a struct or an interface, can't be got by type.
+func OnMissing[T any]() Condition {
+	vt := conditionType[T]()
+
+	return func(ctx context.Context, c *Container) bool {
+		_, err := c.context.lookupItemByType(ctx, vt)
+		if err != nil && !isNotFound(err) {
+			panic(err)
+		}
+		return err != nil
+	}
+}
+
+// OnPresent returns a Condition which holds when T, a struct or an interface, can be got by type.
+func OnPresent[T any]() Condition {
+	missing := OnMissing[T]()
+
+	return func(ctx context.Context, c *Container) bool {
+		return !missing(ctx, c)
+	}
+}
+
+func conditionType[T any]() reflect.Type {
+	vt := reflect.TypeOf((*T)(nil))
+	if vt.Elem().Kind() == reflect.Interface {
+		return vt.Elem()
+	}
+	return vt
+}
+
+type conditionState int
+
+const (
+	conditionPending conditionState = iota
+	conditionEvaluating
+	conditionEnabled
+	conditionDisabled
+)
+
+// itemConditions are the conditions of a registration, they're evaluated once,
+// at container start or when the registration is looked up for the first time.
+type itemConditions struct {
+	context    *factoryContext
+	conditions []Condition
+	state      conditionState
+	done       chan struct{}
+	lock       sync.Mutex
+}
+
+// addCondition makes cci only take effect when all its conditions hold.
+func (c *factoryContext) addCondition(cci *contextCachedItem, condition Condition) {
+	if cci.conditions == nil {
+		cci.conditions = &itemConditions{context: c, lock: sync.NewMutex()}
+	}
+
+	ic := cci.conditions
+	ic.lock.Lock()
+	defer ic.lock.Unlock()
+
+	if ic.state != conditionPending {
+		panic(fmt.Errorf("conditions of %s already evaluated", cci._type.String()))
+	}
+
+	ic.conditions = append(ic.conditions, condition)
+}
+
+// enabled reports whether the conditions of cci hold. They're evaluated once with ctx, and cci is pushed into its getters
+// while they're evaluated, so it's treated as missing by the lookups they make, even the ones of the objects built by them.
+// The other lookups wait for the result.
+func (cci *contextCachedItem) enabled(ctx context.Context) bool {
+	ic := cci.conditions
+	if ic == nil {
+		return true
+	}
+
+	ctx, getters := getterStack(ctx)
+
+	ic.lock.Lock()
+	switch ic.state {
+	case conditionEnabled, conditionDisabled:
+		ic.lock.Unlock()
+		return ic.state == conditionEnabled
+	case conditionEvaluating:
+		if getters.contains(cci) {
+			ic.lock.Unlock()
+			return false
+		}
+
+		done := ic.done
+		ic.lock.Unlock()
+		<-done
+		return cci.enabled(ctx)
+	}
+
+	ic.state = conditionEvaluating
+	ic.done = make(chan struct{})
+	ic.lock.Unlock()
+
+	state := conditionPending
+	defer func() {
+		ic.lock.Lock()
+		ic.state = state
+		close(ic.done)
+		ic.lock.Unlock()
+	}()
+
+	if !getters.Push(cci) {
+		panic(newResolveError(ErrCircular, nil, "conditions of %s, possible circular reference with %s", cci._type.String(), lastGetter(ctx)))
+	}
+	defer getters.Pop()
+
+	container := &Container{context: ic.context}
+	for _, condition := range ic.conditions {
+		if !condition(ctx, container) {
+			state = conditionDisabled
+			return false
+		}
+	}
+
+	state = conditionEnabled
+	return true
+}
@@ -0,0 +1,109 @@
+package factory
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type condCache interface {
+	Kind() string
+}
+
+type condRedis struct{}
+
+func (r *condRedis) Kind() string { return "redis" }
+
+type condMemory struct{}
+
+func (m *condMemory) Kind() string { return "memory" }
+
+type condMetrics struct{}
+
+type condReporter struct {
+	Metrics *condMetrics `wire:"auto"`
+}
+
+type condService struct {
+	Cache condCache `wire:"type"`
+}
+
+func newCondContainer() *Container {
+	c := NewContainer()
+	c.Singleton(new(condMemory)).If(OnMissing[condCache]())
+	c.Singleton(new(condRedis)).When("${env.FACTORY_TEST_CACHE == 'redis'}")
+	c.Singleton(new(condReporter)).If(OnPresent[condMetrics]())
+	c.Singleton(new(condService))
+	return c
+}
+
+func TestConditionWhen(t *testing.T) {
+	t.Setenv("FACTORY_TEST_CACHE", "redis")
+
+	c := newCondContainer()
+	assert.Nil(t, c.Start(context.Background()))
+
+	var service *condService
+	c.Find(&service)
+	assert.Equal(t, "redis", service.Cache.Kind())
+
+	var memory *condMemory
+	assert.ErrorIs(t, c.TryFind(&memory), ErrNotFound)
+
+	var reporter *condReporter
+	assert.ErrorIs(t, c.TryFind(&reporter), ErrNotFound)
+}
+
+func TestConditionOnMissing(t *testing.T) {
+	c := newCondContainer()
+	c.Singleton(new(condMetrics))
+
+	// evaluated on the first lookup without Start
+	var service *condService
+	c.Find(&service)
+	assert.Equal(t, "memory", service.Cache.Kind())
+
+	var reporter *condReporter
+	c.Find(&reporter)
+	assert.NotNil(t, reporter.Metrics)
+
+	assert.Nil(t, c.Validate())
+}
+
+func TestConditionError(t *testing.T) {
+	c := NewContainer()
+	c.Singleton(new(condRedis)).When("${1 + 1}")
+
+	assert.EqualError(t, c.Start(context.Background()), "condition of *factory.condRedis err: condition ${1 + 1} must be evaluated to a bool, got 2")
+}
+
+type condProbe struct {
+	Guarded *condGuarded `wire:"auto,optional"`
+}
+
+type condGuarded struct{}
+
+func TestConditionReentrant(t *testing.T) {
+	c := NewContainer()
+	c.NamedSingleton("probe", new(condProbe))
+	c.Singleton(new(condGuarded)).When("${probe != nil}")
+
+	// the probe built by the condition is wired in another goroutine, it sees the guarded one missing
+	done := make(chan error, 1)
+	go func() {
+		var guarded *condGuarded
+		done <- c.TryFind(&guarded)
+	}()
+
+	select {
+	case err := <-done:
+		assert.Nil(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("deadlock")
+	}
+
+	var probe *condProbe
+	c.FindByName("probe", &probe)
+	assert.Nil(t, probe.Guarded)
+}
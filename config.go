@@ -2,7 +2,6 @@ package factory
 
 import (
 	"context"
-	"fmt"
 	"os"
 	"reflect"
 	"strconv"
@@ -12,16 +11,22 @@ import (
 const TimeoutKey = "Timeout"
 const GetterKey = "Getter"
 const TypeKey = "type"
+const ContainerKey = "Container"
+const ScopeKey = "Scope"
+const ExprScopeKey = "ExprScope"
 
 var Opts = struct {
 	EnableTimeout   bool
 	Timeout         time.Duration
 	TimeoutInterval time.Duration
+	DestroyTimeout  time.Duration
+	Profiles        []string // active in the containers created after it's set, see ActivateProfiles
 	Log             Logger
 }{
 	EnableTimeout:   false,
 	Timeout:         3 * time.Second,
 	TimeoutInterval: 100 * time.Millisecond,
+	DestroyTimeout:  3 * time.Second,
 	Log:             &logger{},
 }
 
@@ -40,6 +45,18 @@ func init() {
 		Opts.TimeoutInterval = time.Duration(n) * time.Millisecond
 		Opts.Log.Debugf("TimeoutInterval set to %v", Opts.TimeoutInterval)
 	}
+
+	if n, _ := strconv.Atoi(os.Getenv("FACTORY_DESTROY_TIMEOUT")); n > 0 {
+		Opts.DestroyTimeout = time.Duration(n) * time.Second
+		Opts.Log.Debugf("DestroyTimeout set to %v", Opts.DestroyTimeout)
+	}
+
+	if profiles := splitProfiles(os.Getenv("FACTORY_PROFILES")); len(profiles) > 0 {
+		Opts.Profiles = profiles
+		// _context is created before
+		_context.activateProfiles(profiles)
+		Opts.Log.Debugf("Profiles set to %v", Opts.Profiles)
+	}
 }
 
 func getTimeoutContext(timeout time.Duration) context.Context {
@@ -51,7 +68,7 @@ func getNextTimeoutContext(ctx context.Context) context.Context {
 		duration := getContextTimeout(ctx)
 		duration -= Opts.TimeoutInterval
 		if duration <= 0 {
-			panic("need larger DefaultFindTimeout")
+			panic(newResolveError(ErrTimeout, nil, "need larger DefaultFindTimeout"))
 		}
 		return context.WithValue(ctx, TimeoutKey, duration)
 	} else {
@@ -72,16 +89,42 @@ func getContextTimeout(ctx context.Context) time.Duration {
 	}
 }
 
-func pushGetter(ctx context.Context, ci *contextCachedItem) context.Context {
+func withContainer(ctx context.Context, c *factoryContext) context.Context {
+	if value := ctx.Value(ContainerKey); value != nil && value.(*factoryContext) == c {
+		return ctx
+	}
+	return context.WithValue(ctx, ContainerKey, c)
+}
+
+func getContextContainer(ctx context.Context) *factoryContext {
+	value := ctx.Value(ContainerKey)
+	if value != nil {
+		return value.(*factoryContext)
+	}
+	return _context
+}
+
+// getterStack returns the stack of the builders being got with ctx, and a ctx holding it.
+func getterStack(ctx context.Context) (context.Context, *setStack) {
 	ciSetValue := ctx.Value(GetterKey)
 	if ciSetValue == nil {
 		ciSetValue = &setStack{}
 		ctx = context.WithValue(ctx, GetterKey, ciSetValue)
 	}
 
-	ciSet := ciSetValue.(*setStack)
+	return ctx, ciSetValue.(*setStack)
+}
+
+func pushGetter(ctx context.Context, ci *contextCachedItem) context.Context {
+	ctx, ciSet := getterStack(ctx)
+	if last, ok := ciSet.Last(); ok {
+		// recorded by the container destroying the dependent
+		from := last.(*contextCachedItem)
+		from.context.addDependency(from, ci)
+	}
+
 	if !ciSet.Push(ci) {
-		panic(fmt.Errorf("getting %s, possible circular reference with %s", ci._type.String(), lastGetter(ctx)))
+		panic(newResolveError(ErrCircular, nil, "getting %s, possible circular reference with %s", ci._type.String(), lastGetter(ctx)))
 	}
 	return ctx
 }
@@ -118,7 +161,7 @@ func pushType(ctx context.Context, _type reflect.Type) context.Context {
 
 	typeSet := typeSetValue.(*setStack)
 	if !typeSet.Push(_type) {
-		panic(fmt.Errorf("getting %s, possible circular reference with %s", _type.String(), lastType(ctx)))
+		panic(newResolveError(ErrCircular, nil, "getting %s, possible circular reference with %s", _type.String(), lastType(ctx)))
 	}
 	return ctx
 }
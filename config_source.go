@@ -0,0 +1,418 @@
+package factory
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"github.com/expgo/sync"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ConfigLayer is the priority of a ConfigSource, the values of a higher layer override the ones of the lower layers.
+type ConfigLayer int
+
+const (
+	ConfigLayerDefault ConfigLayer = iota
+	ConfigLayerFile
+	ConfigLayerDotEnv
+	ConfigLayerEnv
+	ConfigLayerOverride
+)
+
+var configLayers = []ConfigLayer{ConfigLayerDefault, ConfigLayerFile, ConfigLayerDotEnv, ConfigLayerEnv, ConfigLayerOverride}
+
+// ConfigSource provides the values of a configuration layer.
+type ConfigSource interface {
+	// Load returns the values, as nested maps for structured sources such as YAML files,
+	// or flat keys such as DB_HOST for env sources.
+	Load() (map[string]any, error)
+}
+
+// ConfigSourceFunc is a func used as a ConfigSource.
+type ConfigSourceFunc func() (map[string]any, error)
+
+func (f ConfigSourceFunc) Load() (map[string]any, error) {
+	return f()
+}
+
+// MapSource returns a ConfigSource of values, it's used for defaults and overrides.
+func MapSource(values map[string]any) ConfigSource {
+	return ConfigSourceFunc(func() (map[string]any, error) {
+		return values, nil
+	})
+}
+
+// envSource is a ConfigSource of the OS env. Its keys only override the nested values of the lower layers
+// when they have the prefix, which is cut from them, such as APP_DB_HOST for db.host with the prefix APP_,
+// so the unrelated vars, such as PATH or USER, never override the values of files.
+type envSource struct {
+	prefix string
+}
+
+// EnvSource returns a ConfigSource of the OS env, its keys are only used by the env style lookups, such as struct binding.
+func EnvSource() ConfigSource {
+	return &envSource{}
+}
+
+// PrefixedEnvSource returns a ConfigSource of the OS env, the keys with prefix also override the nested values
+// of the lower layers, with prefix cut, such as APP_DB_HOST for db.host with the prefix APP_.
+func PrefixedEnvSource(prefix string) ConfigSource {
+	return &envSource{prefix: prefix}
+}
+
+func (s *envSource) Load() (map[string]any, error) {
+	result := map[string]any{}
+	for k, v := range *envToMap(os.Environ()) {
+		result[k] = v
+	}
+	return result, nil
+}
+
+// overrides returns the values with the prefix, which override the nested values, the prefix is cut from their keys.
+func (s *envSource) overrides(values map[string]any) map[string]any {
+	result := map[string]any{}
+	if len(s.prefix) == 0 {
+		return result
+	}
+
+	for k, v := range values {
+		if key, ok := strings.CutPrefix(k, s.prefix); ok && len(key) > 0 {
+			result[key] = v
+		}
+	}
+	return result
+}
+
+// fileSource is a ConfigSource of a local file, which is polled by WatchConfig.
+type fileSource struct {
+	path  string
+	parse func(data []byte) (map[string]any, error)
+	// a missing file has no values
+	optional bool
+}
+
+func (s *fileSource) Load() (map[string]any, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if s.optional && os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return s.parse(data)
+}
+
+// DotEnvSource returns a ConfigSource of a .env file, which has a KEY=VALUE pair each line.
+// A missing file has no values.
+func DotEnvSource(path string) ConfigSource {
+	return &fileSource{path: path, parse: parseDotEnv, optional: true}
+}
+
+// FileSource returns a ConfigSource of a YAML, JSON or TOML file, the format is chosen by the file extension.
+func FileSource(path string) ConfigSource {
+	return &fileSource{path: path, parse: func(data []byte) (result map[string]any, err error) {
+		result = map[string]any{}
+		switch ext := strings.ToLower(filepath.Ext(path)); ext {
+		case ".yaml", ".yml":
+			err = yaml.Unmarshal(data, &result)
+		case ".json":
+			err = json.Unmarshal(data, &result)
+		case ".toml":
+			err = toml.Unmarshal(data, &result)
+		default:
+			err = fmt.Errorf("config file format %s not supported", ext)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("load config file %s err: %w", path, err)
+		}
+
+		return result, nil
+	}}
+}
+
+func parseDotEnv(data []byte) (map[string]any, error) {
+	result := map[string]any{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if len(text) == 0 || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		text = strings.TrimPrefix(text, "export ")
+		key, value, ok := strings.Cut(text, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid .env line %d: %s", line, text)
+		}
+
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+
+		result[strings.TrimSpace(key)] = value
+	}
+
+	return result, scanner.Err()
+}
+
+// configValues are the merged values of all sources.
+type configValues struct {
+	nested map[string]any    // exposed as 'config' to exprs
+	flat   map[string]string // env style keys, such as DB_HOST for db.host, used by struct binding
+}
+
+// configResolver is the 'config' named singleton, exprs see its current values, see exprValuer.
+type configResolver struct {
+	sources map[ConfigLayer][]ConfigSource
+	values  *configValues
+	lock    sync.RWMutex
+	parent  *configResolver // the sources of a child container are merged over its parent's
+}
+
+func newConfigResolver() *configResolver {
+	return &configResolver{
+		sources: map[ConfigLayer][]ConfigSource{
+			ConfigLayerEnv: {EnvSource()},
+		},
+		lock: sync.NewRWMutex(),
+	}
+}
+
+func (r *configResolver) child() *configResolver {
+	return &configResolver{
+		sources: map[ConfigLayer][]ConfigSource{},
+		lock:    sync.NewRWMutex(),
+		parent:  r,
+	}
+}
+
+// AddConfigSource adds source to the layer of the default container's config, see Container.AddConfigSource.
+func AddConfigSource(layer ConfigLayer, source ConfigSource) {
+	_context.addConfigSource(layer, source)
+}
+
+// addConfigSource adds source to the config of c, which is reloaded if it's loaded already,
+// a reload error panics.
+func (c *factoryContext) addConfigSource(layer ConfigLayer, source ConfigSource) {
+	if c.config.addSource(layer, source) {
+		if err := c.reloadConfig(); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// addSource adds source to layer, and reports whether the values are loaded already.
+func (r *configResolver) addSource(layer ConfigLayer, source ConfigSource) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.sources[layer] = append(r.sources[layer], source)
+	return r.values != nil
+}
+
+// layerSources returns the sources of layer, the ones of the parent come first.
+func (r *configResolver) layerSources(layer ConfigLayer) []ConfigSource {
+	var result []ConfigSource
+	if r.parent != nil {
+		result = r.parent.layerSources(layer)
+	}
+
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return append(result, r.sources[layer]...)
+}
+
+// load merges the values of all sources from the lowest layer to the highest one.
+// A value of a higher layer also overrides the nested value with the same env style key,
+// such as DB_HOST of a .env file overrides db.host of a file, the OS env only does it with a prefix, see PrefixedEnvSource.
+func (r *configResolver) load() (*configValues, error) {
+	result := &configValues{nested: map[string]any{}, flat: map[string]string{}}
+
+	for _, layer := range configLayers {
+		for _, source := range r.layerSources(layer) {
+			values, err := source.Load()
+			if err != nil {
+				return nil, err
+			}
+
+			overrides := values
+			if es, ok := source.(*envSource); ok {
+				flattenConfig(values, nil, func(path []string, value any) {
+					result.flat[configKey(path)] = configString(value)
+				})
+				overrides = es.overrides(values)
+			}
+
+			leaves := map[string][]string{}
+			flattenConfig(result.nested, nil, func(path []string, value any) {
+				leaves[configKey(path)] = path
+			})
+
+			flattenConfig(overrides, nil, func(path []string, value any) {
+				key := configKey(path)
+				if leaf, ok := leaves[key]; ok {
+					setConfigPath(result.nested, leaf, value)
+				}
+				result.flat[key] = configString(value)
+			})
+
+			mergeConfig(result.nested, values)
+		}
+	}
+
+	return result, nil
+}
+
+// set makes values the current ones, and returns the previous ones, which are nil if nothing is loaded.
+func (r *configResolver) set(values *configValues) *configValues {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	old := r.values
+	r.values = values
+	return old
+}
+
+// exprValue returns the nested values, the reloaded ones replace them, but they're never changed.
+func (r *configResolver) exprValue() any {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	if r.values == nil {
+		return map[string]any{}
+	}
+
+	return r.values.nested
+}
+
+func (r *configResolver) lookup(key string) (string, bool, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	if r.values == nil {
+		return "", false, false
+	}
+
+	value, ok := r.values.flat[key]
+	return value, ok, true
+}
+
+// configKey returns the env style key of path, such as DB_HOST for db.host.
+func configKey(path []string) string {
+	key := strings.Join(path, "_")
+	key = strings.NewReplacer(".", "_", "-", "_").Replace(key)
+	return strings.ToUpper(key)
+}
+
+func configString(value any) string {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		var items []string
+		for i := 0; i < rv.Len(); i++ {
+			items = append(items, fmt.Sprint(rv.Index(i).Interface()))
+		}
+		return strings.Join(items, ",")
+	}
+
+	return fmt.Sprint(value)
+}
+
+// flattenConfig calls f with the path of each leaf value of values, in the order of the keys.
+func flattenConfig(values map[string]any, path []string, f func(path []string, value any)) {
+	var keys []string
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		p := append(append([]string{}, path...), k)
+		if m, ok := toConfigMap(values[k]); ok {
+			flattenConfig(m, p, f)
+		} else {
+			f(p, values[k])
+		}
+	}
+}
+
+// mergeConfig deeply merges from into to, the values of from win.
+func mergeConfig(to map[string]any, from map[string]any) {
+	for k, v := range from {
+		if fm, ok := toConfigMap(v); ok {
+			if tm, ok := toConfigMap(to[k]); ok {
+				mergeConfig(tm, fm)
+				to[k] = tm
+				continue
+			}
+
+			copied := map[string]any{}
+			mergeConfig(copied, fm)
+			to[k] = copied
+			continue
+		}
+
+		to[k] = v
+	}
+}
+
+func setConfigPath(values map[string]any, path []string, value any) {
+	for _, k := range path[:len(path)-1] {
+		m, _ := toConfigMap(values[k])
+		values[k] = m
+		values = m
+	}
+	values[path[len(path)-1]] = value
+}
+
+// toConfigMap converts the nested maps decoded from files to map[string]any.
+func toConfigMap(value any) (map[string]any, bool) {
+	switch m := value.(type) {
+	case map[string]any:
+		return m, true
+	case map[any]any:
+		result := map[string]any{}
+		for k, v := range m {
+			result[fmt.Sprint(k)] = v
+		}
+		return result, true
+	}
+
+	return nil, false
+}
+
+func registerConfig(c *factoryContext) {
+	_singletonWithType(c, reflect.TypeOf((*configResolver)(nil))).Name("config").SetInitFunc(func() any {
+		values, err := c.config.load()
+		if err != nil {
+			panic(err)
+		}
+
+		c.config.set(values)
+		return c.config
+	})
+}
+
+// lookupConfig gets the value of the env style key from the config visible from c.
+func (c *factoryContext) lookupConfig(key string) (string, bool) {
+	c.getByNamePanic(c.timeoutContext(Opts.Timeout), "config", nil)
+
+	for fc := c; fc != nil; fc = fc.parent {
+		if value, ok, loaded := fc.config.lookup(key); loaded {
+			return value, ok
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,126 @@
+package factory
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type configServer struct {
+	Host  string `value:"${config.server.host}"`
+	Port  int    `value:"${config.server.port}"`
+	Debug bool   `value:"${config.debug}"`
+}
+
+type configTomlServer struct {
+	Host string `value:"${config.server.host}"`
+	Port int    `value:"${config.server.port}"`
+}
+
+type configProps struct {
+	Host string   `env:"HOST"`
+	Port int      `env:"PORT"`
+	Tags []string `env:"TAGS"`
+}
+
+func writeConfigFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestConfigSourceLayers(t *testing.T) {
+	yamlFile := writeConfigFile(t, "app.yaml", "server:\n  host: yaml.local\n  port: 80\n  tags: [a, b]\ndebug: false\n")
+	jsonFile := writeConfigFile(t, "app.json", `{"server": {"port": 8080}}`)
+	dotEnvFile := writeConfigFile(t, ".env", "# comment\nexport SERVER_HOST=\"dotenv.local\"\nDEBUG=true\n")
+	t.Setenv("APP_SERVER_PORT", "9090")
+	// without the prefix, only used by the env style lookups
+	t.Setenv("SERVER_TAGS", "c")
+
+	c := NewContainer()
+	c.AddConfigSource(ConfigLayerDefault, MapSource(map[string]any{"server": map[string]any{"host": "default.local", "port": 1}}))
+	c.AddConfigSource(ConfigLayerFile, FileSource(yamlFile))
+	c.AddConfigSource(ConfigLayerFile, FileSource(jsonFile))
+	c.AddConfigSource(ConfigLayerDotEnv, DotEnvSource(dotEnvFile))
+	c.AddConfigSource(ConfigLayerEnv, PrefixedEnvSource("APP_"))
+	c.Singleton(new(configServer))
+	c.ConfigProperties(new(configProps), "SERVER_")
+
+	var server *configServer
+	c.Find(&server)
+	assert.Equal(t, &configServer{Host: "dotenv.local", Port: 9090, Debug: true}, server)
+
+	var props *configProps
+	c.Find(&props)
+	assert.Equal(t, &configProps{Host: "dotenv.local", Port: 9090, Tags: []string{"c"}}, props)
+	assert.Equal(t, []any{"a", "b"}, c.context.config.exprValue().(map[string]any)["server"].(map[string]any)["tags"])
+
+	c.AddConfigSource(ConfigLayerOverride, MapSource(map[string]any{"SERVER_HOST": "override.local"}))
+	values, err := c.context.config.load()
+	assert.Nil(t, err)
+	assert.Equal(t, "override.local", values.nested["server"].(map[string]any)["host"])
+}
+
+func TestConfigSourceToml(t *testing.T) {
+	tomlFile := writeConfigFile(t, "app.toml", "[server]\nhost = \"toml.local\"\nport = 7070\n")
+
+	c := NewContainer()
+	c.AddConfigSource(ConfigLayerFile, FileSource(tomlFile))
+	c.Singleton(new(configTomlServer))
+
+	var server *configTomlServer
+	c.Find(&server)
+	assert.Equal(t, &configTomlServer{Host: "toml.local", Port: 7070}, server)
+}
+
+func TestConfigSourceError(t *testing.T) {
+	c := NewContainer()
+	c.AddConfigSource(ConfigLayerFile, FileSource(writeConfigFile(t, "app.ini", "a=b")))
+	c.Singleton(new(configServer))
+
+	var server *configServer
+	assert.ErrorContains(t, c.TryFind(&server), "config file format .ini not supported")
+
+	_, err := parseDotEnv([]byte("A=1\nB"))
+	assert.EqualError(t, err, "invalid .env line 2: B")
+}
+
+func TestConfigSourceChild(t *testing.T) {
+	parent := NewContainer()
+	parent.AddConfigSource(ConfigLayerFile, MapSource(map[string]any{"server": map[string]any{"host": "parent.local", "port": 80}}))
+
+	child := parent.Child()
+	child.AddConfigSource(ConfigLayerFile, MapSource(map[string]any{"server": map[string]any{"host": "child.local"}}))
+	child.Singleton(new(configServer))
+	parent.Singleton(new(configServer))
+
+	var server *configServer
+	child.Find(&server)
+	assert.Equal(t, &configServer{Host: "child.local", Port: 80}, server)
+
+	parent.Find(&server)
+	assert.Equal(t, &configServer{Host: "parent.local", Port: 80}, server)
+}
+
+type configRefreshed struct {
+	Host string `value:"${config.server.host}"`
+}
+
+func TestConfigSourceAddedAfterLoad(t *testing.T) {
+	c := NewContainer()
+	c.AddConfigSource(ConfigLayerFile, MapSource(map[string]any{"server": map[string]any{"host": "file.local"}}))
+	c.Singleton(new(configRefreshed)).Refreshable()
+
+	var server *configRefreshed
+	c.Find(&server)
+	assert.Equal(t, "file.local", server.Host)
+
+	// reloaded
+	c.AddConfigSource(ConfigLayerOverride, MapSource(map[string]any{"server": map[string]any{"host": "override.local"}}))
+	assert.Equal(t, "override.local", server.Host)
+
+	assert.Panics(t, func() {
+		c.AddConfigSource(ConfigLayerFile, FileSource(writeConfigFile(t, "app.ini", "a=b")))
+	})
+}
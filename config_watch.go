@@ -0,0 +1,220 @@
+package factory
+
+import (
+	"context"
+	"fmt"
+	"github.com/expgo/structure"
+	"os"
+	"reflect"
+	"time"
+)
+
+// ConfigChangeListener is implemented by the objects notified when the config is reloaded with changed values.
+type ConfigChangeListener interface {
+	OnConfigChange(old, new map[string]any)
+}
+
+// ReloadConfig reloads the config of the default container, see Container.ReloadConfig.
+func ReloadConfig() error {
+	return _context.reloadConfig()
+}
+
+// WatchConfig polls the config files of the default container, see Container.WatchConfig.
+func WatchConfig(ctx context.Context, interval time.Duration) {
+	_context.watchConfig(ctx, interval, logReloadError)
+}
+
+// WatchConfigFunc polls the config files of the default container, see Container.WatchConfigFunc.
+func WatchConfigFunc(ctx context.Context, interval time.Duration, onError func(err error)) {
+	_context.watchConfig(ctx, interval, onError)
+}
+
+// ReloadConfig loads all config sources of c again. If the values are changed, the '${}' fields of the
+// Refreshable singletons are re-evaluated, and the created objects implementing ConfigChangeListener are notified,
+// in c and its children. All errors are returned together.
+func (c *Container) ReloadConfig() error {
+	return c.context.reloadConfig()
+}
+
+// WatchConfig polls the files of the config sources of c every interval until ctx is done,
+// the config is reloaded when one of them is changed. The reload errors are logged by Opts.Log,
+// at the error level if it's an ErrorLogger.
+func (c *Container) WatchConfig(ctx context.Context, interval time.Duration) {
+	c.context.watchConfig(ctx, interval, logReloadError)
+}
+
+// WatchConfigFunc is like WatchConfig, but the reload errors are passed to onError.
+func (c *Container) WatchConfigFunc(ctx context.Context, interval time.Duration, onError func(err error)) {
+	c.context.watchConfig(ctx, interval, onError)
+}
+
+func logReloadError(err error) {
+	logErrorf("reload config err: %v", err)
+}
+
+func (c *factoryContext) reloadConfig() error {
+	if errs := c.reloadConfigs(); len(errs) > 0 {
+		return &Error{Errors: errs}
+	}
+
+	return nil
+}
+
+// reloadConfigs reloads the config of c, then the ones of its children, which are merged over it.
+func (c *factoryContext) reloadConfigs() (errs []string) {
+	values, err := c.config.load()
+	if err != nil {
+		return appendErrors(errs, err)
+	}
+
+	old := c.config.set(values)
+	errs = c.configReloaded(old, values)
+
+	for _, child := range c.getChildren() {
+		errs = append(errs, child.reloadConfigs()...)
+	}
+
+	return
+}
+
+// configReloaded refreshes the objects of c bound to its config, which is reloaded from old to values.
+func (c *factoryContext) configReloaded(old, values *configValues) (errs []string) {
+	// the identifiers are resolved again by the next evaluations
+	c.exprEnvLock.Lock()
+	c.exprEnvMap = make(map[string]any)
+	c.exprEnvLock.Unlock()
+
+	if old != nil && !reflect.DeepEqual(old.nested, values.nested) {
+		// otherwise nothing is bound to the config yet, or nothing changed
+		c.lifecycle.lock.Lock()
+		created := append([]*createdObject{}, c.lifecycle.created...)
+		c.lifecycle.lock.Unlock()
+
+		ctx := withContainer(c.timeoutContext(Opts.Timeout), c)
+
+		for _, co := range created {
+			if co.cci.refreshable {
+				if err := try(func() { refreshValues(ctx, co.obj) }); err != nil {
+					errs = appendErrors(errs, fmt.Errorf("refresh %s err: %w", co.cci._type.String(), err))
+				}
+			}
+		}
+
+		for _, co := range created {
+			if listener, ok := co.obj.(ConfigChangeListener); ok {
+				if err := try(func() { listener.OnConfigChange(old.nested, values.nested) }); err != nil {
+					errs = appendErrors(errs, fmt.Errorf("notify %s err: %w", co.cci._type.String(), err))
+				}
+			}
+		}
+	}
+
+	return
+}
+
+// locker is implemented by the Refreshable objects guarding their fields, such as the ones embedding a sync.RWMutex.
+type locker interface {
+	Lock()
+	Unlock()
+}
+
+// refreshValues evaluates the fields with 'value' tags of self again, the objects self refers to,
+// such as its wired dependencies, aren't refreshed. All fields are evaluated before any of them is set,
+// then they're set together, while self is locked if it's a locker, readers holding its lock never see
+// a partially refreshed object. Otherwise the fields are set without any synchronization.
+func refreshValues(ctx context.Context, self any) {
+	ctx, scope := withExprScope(ctx, self)
+
+	var sets []func() error
+
+	err := walkOwnTags(self, []string{TagWire.Name(), TagValue.Name(), TagNew.Name()}, func(fieldValue reflect.Value, structField reflect.StructField, rootValues []reflect.Value, tags map[string]string) error {
+		valueTag, ok := tags[TagValue.Name()]
+		if !ok {
+			scope.wired(fieldValue, structField, rootValues)
+			return nil
+		}
+		tv := parseValueTag(valueTag)
+		tv.Layout = structField.Tag.Get(LayoutTagName)
+
+		value, found, err := getOptionalValueByWireTag(ctx, self, tv, structField.Type)
+		if err != nil {
+			return fmt.Errorf("%w on %s", err, structure.GetFieldPath(structField, rootValues))
+		}
+		if !found {
+			scope.wired(fieldValue, structField, rootValues)
+			return nil
+		}
+
+		// the exprs of the following fields see the new value
+		scope.set(structField, rootValues, value)
+
+		parent := rootValues[len(rootValues)-1]
+		sets = append(sets, func() error {
+			if structure.SetFieldBySetMethod(fieldValue, value, structField, parent) {
+				return nil
+			}
+			return structure.SetField(fieldValue, value)
+		})
+		return nil
+	})
+
+	if err == nil {
+		if l, ok := self.(locker); ok {
+			l.Lock()
+			defer l.Unlock()
+		}
+
+		for _, set := range sets {
+			if err = set(); err != nil {
+				break
+			}
+		}
+	}
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (c *factoryContext) watchConfig(ctx context.Context, interval time.Duration, onError func(err error)) {
+	stats := c.config.fileStats()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			if current := c.config.fileStats(); !reflect.DeepEqual(current, stats) {
+				stats = current
+				if err := c.reloadConfig(); err != nil && onError != nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+}
+
+// fileStats returns the modification time and size of each config file, including the parent's ones,
+// a missing file has an empty stat.
+func (r *configResolver) fileStats() map[string]string {
+	result := map[string]string{}
+	for _, layer := range configLayers {
+		for _, source := range r.layerSources(layer) {
+			if fs, ok := source.(*fileSource); ok {
+				if info, err := os.Stat(fs.path); err == nil {
+					result[fs.path] = fmt.Sprintf("%d:%d", info.ModTime().UnixNano(), info.Size())
+				} else {
+					result[fs.path] = ""
+				}
+			}
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,224 @@
+package factory
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+type watchLimiter struct {
+	Level string `value:"${config.log.level}"`
+	Rate  int    `value:"${config.rate}"`
+}
+
+type watchRate struct {
+	Rate int `value:"${config.rate}"`
+}
+
+type watchConsumer struct {
+	Rate int        `value:"${config.rate}"`
+	Dep  *watchRate `wire:"auto"`
+}
+
+type watchListener struct {
+	changes chan string
+}
+
+func (l *watchListener) OnConfigChange(old, new map[string]any) {
+	l.changes <- old["rate"].(string) + "->" + new["rate"].(string)
+}
+
+func TestConfigReload(t *testing.T) {
+	file := writeConfigFile(t, "app.yaml", "log:\n  level: info\nrate: \"10\"\n")
+
+	c := NewContainer()
+	c.AddConfigSource(ConfigLayerFile, FileSource(file))
+	c.Singleton(new(watchLimiter)).Refreshable()
+	c.NamedSingleton("static", new(watchLimiter))
+	c.Singleton(&watchListener{}).SetInitFunc(func() any { return &watchListener{changes: make(chan string, 1)} })
+
+	var limiter *watchLimiter
+	c.Find(&limiter)
+	var static *watchLimiter
+	c.FindByName("static", &static)
+	var listener *watchListener
+	c.Find(&listener)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c.WatchConfig(ctx, 10*time.Millisecond)
+
+	assert.Nil(t, os.WriteFile(file, []byte("log:\n  level: debug\nrate: \"20\"\n"), 0644))
+	assert.Nil(t, os.Chtimes(file, time.Now(), time.Now().Add(time.Second)))
+
+	select {
+	case change := <-listener.changes:
+		assert.Equal(t, "10->20", change)
+	case <-time.After(time.Second):
+		assert.Fail(t, "config change not notified")
+	}
+
+	assert.Equal(t, &watchLimiter{Level: "debug", Rate: 20}, limiter)
+	assert.Equal(t, &watchLimiter{Level: "info", Rate: 10}, static)
+
+	// unchanged values notify nothing
+	assert.Nil(t, c.ReloadConfig())
+	assert.Len(t, listener.changes, 0)
+}
+
+func TestConfigReloadError(t *testing.T) {
+	file := writeConfigFile(t, "app.json", `{"rate": "10"}`)
+
+	c := NewContainer()
+	c.AddConfigSource(ConfigLayerFile, FileSource(file))
+	c.Singleton(new(watchRate)).Refreshable()
+	c.Find(new(*watchRate))
+
+	assert.Nil(t, os.WriteFile(file, []byte(`{"rate": "abc"}`), 0644))
+	assert.ErrorContains(t, c.ReloadConfig(), "refresh *factory.watchRate err:")
+
+	assert.Nil(t, os.WriteFile(file, []byte(`{"rate": `), 0644))
+	assert.ErrorContains(t, c.ReloadConfig(), "load config file")
+}
+
+func TestConfigReloadDependency(t *testing.T) {
+	file := writeConfigFile(t, "app.json", `{"rate": "1"}`)
+
+	c := NewContainer()
+	c.AddConfigSource(ConfigLayerFile, FileSource(file))
+	c.Singleton(new(watchRate))
+	c.Singleton(new(watchConsumer)).Refreshable()
+
+	var consumer *watchConsumer
+	c.Find(&consumer)
+
+	assert.Nil(t, os.WriteFile(file, []byte(`{"rate": "2"}`), 0644))
+	assert.Nil(t, c.ReloadConfig())
+
+	// the wired dependency isn't Refreshable
+	assert.Equal(t, 2, consumer.Rate)
+	assert.Equal(t, 1, consumer.Dep.Rate)
+}
+
+func TestConfigReloadConcurrent(t *testing.T) {
+	file := writeConfigFile(t, "app.json", `{"rate": "10"}`)
+
+	c := NewContainer()
+	c.AddConfigSource(ConfigLayerFile, FileSource(file))
+	c.Prototype(new(watchRate))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			var rate *watchRate
+			c.New(&rate)
+		}
+	}()
+
+	for i := 0; i < 50; i++ {
+		assert.Nil(t, c.ReloadConfig())
+	}
+	<-done
+}
+
+func TestConfigReloadChild(t *testing.T) {
+	file := writeConfigFile(t, "app.json", `{"rate": "10"}`)
+
+	parent := NewContainer()
+	parent.AddConfigSource(ConfigLayerFile, FileSource(file))
+	child := parent.Child()
+	child.Singleton(new(watchRate)).Refreshable()
+	child.Singleton(&watchListener{}).SetInitFunc(func() any { return &watchListener{changes: make(chan string, 1)} })
+
+	var rate *watchRate
+	child.Find(&rate)
+	var listener *watchListener
+	child.Find(&listener)
+
+	assert.Nil(t, os.WriteFile(file, []byte(`{"rate": "20"}`), 0644))
+	assert.Nil(t, parent.ReloadConfig())
+
+	assert.Equal(t, 20, rate.Rate)
+	assert.Equal(t, "10->20", <-listener.changes)
+}
+
+type watchLocked struct {
+	sync.RWMutex
+	Low  int `value:"${config.low}"`
+	High int `value:"${config.high}"`
+}
+
+func TestConfigReloadLocked(t *testing.T) {
+	values := map[string]any{"low": "0", "high": "0"}
+	lock := sync.Mutex{}
+
+	c := NewContainer()
+	c.AddConfigSource(ConfigLayerOverride, ConfigSourceFunc(func() (map[string]any, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		return map[string]any{"low": values["low"], "high": values["high"]}, nil
+	}))
+	c.Singleton(new(watchLocked)).Refreshable()
+
+	var locked *watchLocked
+	c.Find(&locked)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i <= 50; i++ {
+			lock.Lock()
+			values["low"], values["high"] = fmt.Sprint(i), fmt.Sprint(i)
+			lock.Unlock()
+			assert.Nil(t, c.ReloadConfig())
+		}
+	}()
+
+	for {
+		select {
+		case <-done:
+			assert.Equal(t, 50, locked.High)
+			return
+		default:
+		}
+
+		// never seen partially refreshed
+		locked.RLock()
+		assert.Equal(t, locked.Low, locked.High)
+		locked.RUnlock()
+	}
+}
+
+func TestWatchConfigFunc(t *testing.T) {
+	file := writeConfigFile(t, "app.json", `{"rate": "10"}`)
+
+	c := NewContainer()
+	c.AddConfigSource(ConfigLayerFile, FileSource(file))
+	c.Singleton(new(watchRate))
+	c.Find(new(*watchRate))
+
+	errs := make(chan error, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c.WatchConfigFunc(ctx, 10*time.Millisecond, func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	})
+
+	assert.Nil(t, os.WriteFile(file, []byte(`{"rate": `), 0644))
+	assert.Nil(t, os.Chtimes(file, time.Now(), time.Now().Add(time.Second)))
+
+	select {
+	case err := <-errs:
+		assert.ErrorContains(t, err, "load config file")
+	case <-time.After(time.Second):
+		assert.Fail(t, "reload error not reported")
+	}
+}
@@ -0,0 +1,243 @@
+package factory
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Container holds its own registries of singletons, interfaces, factories and pools,
+// so that several independent wirings can live in the same process.
+// The package level functions act on the default container.
+type Container struct {
+	context *factoryContext
+}
+
+var defaultContainer = &Container{context: _context}
+
+// DefaultContainer returns the container used by the package level functions.
+func DefaultContainer() *Container {
+	return defaultContainer
+}
+
+// NewContainer returns an empty container, only the 'env' and 'config' named singletons are registered.
+func NewContainer() *Container {
+	c := &Container{context: newFactoryContext()}
+	registerEnv(c.context)
+	registerConfig(c.context)
+	return c
+}
+
+// Child returns a container scoped under c. Lookups in the child fall back to c when nothing is
+// registered in the child itself, and registrations in the child only override c inside the child.
+func (c *Container) Child() *Container {
+	child := &Container{context: c.context.child()}
+	registerConfig(child.context)
+	return child
+}
+
+// Singleton registers t's type as a singleton, t must be a *struct, such as new(T) or (*T)(nil).
+func (c *Container) Singleton(t any) *singleton {
+	return _singletonWithType(c.context, structPtrType(t)).setType()
+}
+
+// NamedSingleton registers t's type as a singleton only reachable by name, t must be a *struct.
+func (c *Container) NamedSingleton(name string, t any) *singleton {
+	return _singletonWithType(c.context, structPtrType(t)).Name(name)
+}
+
+// ActivateProfiles adds profiles to the ones active in c, besides Opts.Profiles, which is initialized from FACTORY_PROFILES,
+// such as "dev,test". The registrations of c and its children matching the newly activated profiles are registered then.
+func (c *Container) ActivateProfiles(profiles ...string) {
+	c.context.activateProfiles(profiles)
+}
+
+// ProfileActive reports whether one of profiles is active in c or its parents.
+func (c *Container) ProfileActive(profiles ...string) bool {
+	return c.context.profileActive(profiles)
+}
+
+// Scoped registers t's type as a scoped builder, t must be a *struct.
+func (c *Container) Scoped(t any) *scoped {
+	return _scopedWithType(c.context, structPtrType(t)).setType()
+}
+
+// NamedScoped registers t's type as a scoped builder only reachable by name, t must be a *struct.
+func (c *Container) NamedScoped(name string, t any) *scoped {
+	return _scopedWithType(c.context, structPtrType(t)).Name(name)
+}
+
+// Prototype registers t's type as a prototype builder, a new object is built on every resolution, t must be a *struct.
+func (c *Container) Prototype(t any) *prototype {
+	return _prototypeWithType(c.context, structPtrType(t)).setType()
+}
+
+// NamedPrototype registers t's type as a prototype builder only reachable by name, t must be a *struct.
+func (c *Container) NamedPrototype(name string, t any) *prototype {
+	return _prototypeWithType(c.context, structPtrType(t)).Name(name)
+}
+
+// Interface registers an interface builder, t must be a pointer to the interface, such as (*I)(nil).
+func (c *Container) Interface(t any) *iInterface {
+	return _interfaceWithType(c.context, interfacePtrType(t).Elem()).setType()
+}
+
+// NamedInterface registers an interface builder only reachable by name, t must be a pointer to the interface.
+func (c *Container) NamedInterface(name string, t any) *iInterface {
+	return _interfaceWithType(c.context, interfacePtrType(t).Elem()).Name(name)
+}
+
+// AddConfigSource adds source to the layer of c's config, which is exposed as the 'config' named singleton
+// and to '${}' expressions, such as ${config.db.host}. The layers are merged from ConfigLayerDefault to ConfigLayerOverride,
+// the OS env is added to ConfigLayerEnv by default. A flat key of a higher layer, such as DB_HOST, also overrides
+// the nested key with the same env style, such as db.host, the OS env only does it with a prefix, see PrefixedEnvSource.
+// The sources are loaded when 'config' is first built, the config is reloaded when a source is added after that.
+// The sources of a child container are merged over the ones of its parent, layer by layer.
+func (c *Container) AddConfigSource(layer ConfigLayer, source ConfigSource) {
+	c.context.addConfigSource(layer, source)
+}
+
+// ConfigProperties registers t's type as a singleton bound from the config, t must be a *struct.
+// The fields with 'env' tags are set from the env style config keys prefixed with prefix, a 'default' tag is used when the key isn't set,
+// and the keys with `required:"true"` must be set. The fields of nested structs are bound with the prefix added by their 'envPrefix' tags.
+// All missing required keys are reported together when the singleton is built.
+func (c *Container) ConfigProperties(t any, prefix string) *singleton {
+	return c.context.configProperties(structPtrType(t), prefix)
+}
+
+// Factory registers the factory used by 'new' tags, t must be a pointer to the produced type, such as (*I)(nil).
+func (c *Container) Factory(t any, f any) *_factory {
+	return c.context.factoryWithType(reflect.TypeOf(t), f)
+}
+
+// Find sets the registered object into ptr, ptr must be a **struct.
+func (c *Container) Find(ptr any) {
+	c.FindTimeout(ptr, Opts.Timeout)
+}
+
+func (c *Container) FindTimeout(ptr any, timeout time.Duration) {
+	target := targetValue(ptr)
+	target.Set(reflect.ValueOf(c.context.findTimeout(target.Type(), timeout)))
+}
+
+// FindContext sets the registered object into ptr, scoped objects are reused inside the scope held by ctx.
+func (c *Container) FindContext(ctx context.Context, ptr any) {
+	target := targetValue(ptr)
+	target.Set(reflect.ValueOf(c.context.findContext(ctx, target.Type())))
+}
+
+// NewContext creates a new object into ptr, its scoped dependencies are reused inside the scope held by ctx.
+func (c *Container) NewContext(ctx context.Context, ptr any) {
+	target := targetValue(ptr)
+	target.Set(reflect.ValueOf(c.context.newContext(ctx, reflect.New(target.Type().Elem()).Interface(), newDefaultOption)))
+}
+
+// FindByName sets the object registered with name into ptr, ptr must be a **struct.
+func (c *Container) FindByName(name string, ptr any) {
+	c.FindByNameTimeout(name, ptr, Opts.Timeout)
+}
+
+func (c *Container) FindByNameTimeout(name string, ptr any, timeout time.Duration) {
+	target := targetValue(ptr)
+	target.Set(reflect.ValueOf(c.context.findByNameTimeout(target.Type(), name, timeout)))
+}
+
+// New creates, wires and inits a new object into ptr, ptr must be a **struct.
+func (c *Container) New(ptr any) {
+	c.NewWithOption(ptr, newDefaultOption)
+}
+
+func (c *Container) NewWithOption(ptr any, option *Option) {
+	target := targetValue(ptr)
+	target.Set(reflect.ValueOf(initWithOptionTimeout(c.context, reflect.New(target.Type().Elem()).Interface(), option, Opts.Timeout, nil)))
+}
+
+// Get gets an object from the container's pool into ptr, ptr must be a **struct.
+func (c *Container) Get(ptr any) {
+	target := targetValue(ptr)
+	target.Set(reflect.ValueOf(c.context.getPoolByType(target.Type().Elem()).Get()))
+}
+
+// Put puts t back to the container's pool, t must be a *struct.
+func (c *Container) Put(t any) {
+	if reflect.ValueOf(t).IsNil() {
+		return
+	}
+
+	c.context.getPoolByType(structPtrType(t).Elem()).Put(t)
+}
+
+func (c *Container) SetPoolInit(t any, option *Option) {
+	c.context.setPoolInitWithType(structPtrType(t).Elem(), option)
+}
+
+func (c *Container) AutoWire(self any) error {
+	return c.AutoWireTimeout(self, Opts.Timeout)
+}
+
+func (c *Container) AutoWireTimeout(self any, timeout time.Duration) error {
+	return autoWireContext(c.context.timeoutContext(timeout), self)
+}
+
+// Start builds all singletons and interfaces registered in the container up front, except the Lazy singletons,
+// their dependencies are built first. It stops on the first failure and returns it as an error,
+// the failed ones keep failing with it when they're looked up later.
+func (c *Container) Start(ctx context.Context) error {
+	return c.context.start(ctx, true)
+}
+
+// StartCollect is like Start, but it builds all singletons even if some of them fail,
+// and returns all errors together.
+func (c *Container) StartCollect(ctx context.Context) error {
+	return c.context.start(ctx, false)
+}
+
+// Shutdown destroys the objects created by the container in the reverse order of their dependencies.
+// An object is closed if it implements io.Closer, otherwise its destroy method is called,
+// see Option.DestroyMethodName. Every object is given its Option.DestroyTimeout, all errors are returned together.
+func (c *Container) Shutdown(ctx context.Context) error {
+	return c.context.shutdown(ctx)
+}
+
+// Graph returns the builders, factories and pools visible from c and the dependencies between them.
+// The edges declared by 'wire', 'value' and 'new' tags and init params are found without building anything,
+// the other edges seen while building objects are added with the source 'resolved'.
+func (c *Container) Graph() *DependencyGraph {
+	return c.context.graph()
+}
+
+// Validate checks the registrations visible from c without building anything: every 'wire', 'value' and 'new' tag,
+// init params and factory params must refer to a registered and unambiguous builder or factory,
+// init methods must match their params, '${}' expressions must compile, and nothing depends on itself.
+// All problems are returned together as an *Error.
+func (c *Container) Validate() error {
+	return c.context.validate()
+}
+
+func structPtrType(t any) reflect.Type {
+	vt := reflect.TypeOf(t)
+	if vt == nil || vt.Kind() != reflect.Ptr || vt.Elem().Kind() != reflect.Struct {
+		panic(fmt.Errorf("need a pointer to struct, get %v", vt))
+	}
+	return vt
+}
+
+func interfacePtrType(t any) reflect.Type {
+	vt := reflect.TypeOf(t)
+	if vt == nil || vt.Kind() != reflect.Ptr || vt.Elem().Kind() != reflect.Interface {
+		panic(fmt.Errorf("need a pointer to interface, get %v", vt))
+	}
+	return vt
+}
+
+func targetValue(ptr any) reflect.Value {
+	vt := reflect.TypeOf(ptr)
+	if vt == nil || vt.Kind() != reflect.Ptr || vt.Elem().Kind() != reflect.Ptr || vt.Elem().Elem().Kind() != reflect.Struct {
+		panic(newResolveError(ErrInvalidArgument, nil, "need a pointer to a struct pointer, get %v", vt))
+	}
+	if reflect.ValueOf(ptr).IsNil() {
+		panic(newResolveError(ErrInvalidArgument, nil, "need a pointer to a struct pointer, get nil %v", vt))
+	}
+	return reflect.ValueOf(ptr).Elem()
+}
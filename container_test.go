@@ -0,0 +1,111 @@
+package factory
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type containerRepo struct {
+	Name string
+}
+
+type containerService struct {
+	Repo *containerRepo `wire:"auto"`
+	Env  string         `value:"${env.CONTAINER_TEST_ENV ?? 'none'}"`
+}
+
+func TestContainerIsolated(t *testing.T) {
+	for _, name := range []string{"c1", "c2", "c3"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			c := NewContainer()
+			c.Singleton(new(containerRepo)).SetInitFunc(func() any {
+				return &containerRepo{Name: name}
+			})
+
+			var repo *containerRepo
+			c.Find(&repo)
+			assert.Equal(t, name, repo.Name)
+
+			var svc *containerService
+			c.New(&svc)
+			assert.Same(t, repo, svc.Repo)
+			assert.Equal(t, "none", svc.Env)
+		})
+	}
+}
+
+func TestContainerNotShared(t *testing.T) {
+	c := NewContainer()
+	c.NamedSingleton("repo", new(containerRepo))
+
+	var repo *containerRepo
+	c.FindByName("repo", &repo)
+	assert.NotNil(t, repo)
+
+	assert.Panics(t, func() {
+		FindByName[containerRepo]("repo")
+	})
+
+	assert.Panics(t, func() {
+		NewContainer().FindByName("repo", &repo)
+	})
+}
+
+func TestContainerFactory(t *testing.T) {
+	type useFactory struct {
+		Repo *containerRepo `new:""`
+	}
+
+	c := NewContainer()
+	c.Factory(new(containerRepo), func() *containerRepo {
+		return &containerRepo{Name: "factory"}
+	}).CheckValid()
+
+	u := &useFactory{}
+	assert.NoError(t, c.AutoWire(u))
+	assert.Equal(t, "factory", u.Repo.Name)
+
+	assert.Error(t, AutoWire(&useFactory{}))
+}
+
+type childPlugin struct {
+	Repo    *containerRepo    `wire:"auto"`
+	Service *containerService `wire:"auto"`
+}
+
+func TestContainerChild(t *testing.T) {
+	parent := NewContainer()
+	parent.Singleton(new(containerRepo)).SetInitFunc(func() any {
+		return &containerRepo{Name: "parent"}
+	})
+	parent.Singleton(new(containerService))
+
+	child := parent.Child()
+	child.Singleton(new(containerRepo)).SetInitFunc(func() any {
+		return &containerRepo{Name: "child"}
+	})
+
+	var childRepo, parentRepo *containerRepo
+	child.Find(&childRepo)
+	parent.Find(&parentRepo)
+	assert.Equal(t, "child", childRepo.Name)
+	assert.Equal(t, "parent", parentRepo.Name)
+
+	var plugin *childPlugin
+	child.New(&plugin)
+	assert.Same(t, childRepo, plugin.Repo)
+
+	// the shared singleton is wired inside the parent
+	var parentService *containerService
+	parent.Find(&parentService)
+	assert.Same(t, parentService, plugin.Service)
+	assert.Same(t, parentRepo, plugin.Service.Repo)
+
+	// named builders fall back to the parent too
+	assert.NotPanics(t, func() {
+		child.context.getByNamePanic(child.context.timeoutContext(Opts.Timeout), "env", nil)
+	})
+}
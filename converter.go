@@ -0,0 +1,160 @@
+package factory
+
+import (
+	"encoding"
+	"fmt"
+	"github.com/expgo/structure"
+	"github.com/expgo/sync"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LayoutTagName is the tag of the layout a time.Time field is parsed with, such as `value:"${env.START}" layout:"2006-01-02"`.
+// The layout is time.RFC3339 if the tag isn't set.
+const LayoutTagName = "layout"
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+var converters = map[reflect.Type]func(string) (any, error){}
+var convertersLock = sync.NewRWMutex()
+
+func init() {
+	RegisterConverter(time.ParseDuration)
+	RegisterConverter(func(s string) (time.Time, error) { return time.Parse(time.RFC3339, s) })
+	RegisterConverter(url.Parse)
+	RegisterConverter(func(s string) (net.IP, error) {
+		if ip := net.ParseIP(s); ip != nil {
+			return ip, nil
+		}
+		return nil, fmt.Errorf("invalid IP address: %s", s)
+	})
+	RegisterConverter(regexp.Compile)
+	RegisterConverter(func(s string) (os.FileMode, error) {
+		mode, err := strconv.ParseUint(s, 8, 32)
+		return os.FileMode(mode), err
+	})
+	RegisterConverter(ParseByteSize)
+}
+
+// RegisterConverter registers f to convert the strings set into the fields of T by 'value' tags and ConfigProperties,
+// such as RegisterConverter(time.ParseDuration). It replaces the converter of T registered before.
+// Without a converter, the types implementing encoding.TextUnmarshaler are unmarshaled from the strings.
+func RegisterConverter[T any](f func(string) (T, error)) {
+	convertersLock.Lock()
+	defer convertersLock.Unlock()
+
+	converters[reflect.TypeOf((*T)(nil)).Elem()] = func(s string) (any, error) {
+		return f(s)
+	}
+}
+
+func getConverter(t reflect.Type) (func(string) (any, error), bool) {
+	convertersLock.RLock()
+	defer convertersLock.RUnlock()
+
+	f, ok := converters[t]
+	return f, ok
+}
+
+// isConvertible reports whether a string is converted to t by a converter or encoding.TextUnmarshaler.
+func isConvertible(t reflect.Type) bool {
+	if _, ok := getConverter(t); ok {
+		return true
+	}
+
+	return t.Implements(textUnmarshalerType) || reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// convertValue converts value to t. A string is converted by the converter registered for t,
+// or unmarshaled if t implements encoding.TextUnmarshaler, a time.Time is parsed with layout if it isn't empty.
+// Other values are converted by structure.ConvertToType.
+func convertValue(value any, t reflect.Type, layout string) (any, error) {
+	if value != nil && reflect.TypeOf(value).AssignableTo(t) {
+		return value, nil
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return structure.ConvertToType(value, t)
+	}
+
+	if t == reflect.TypeOf(time.Time{}) && len(layout) > 0 {
+		return time.Parse(layout, s)
+	}
+
+	if f, ok := getConverter(t); ok {
+		return f(s)
+	}
+
+	if t.Kind() == reflect.Ptr && t.Implements(textUnmarshalerType) {
+		result := reflect.New(t.Elem())
+		if err := result.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+			return nil, err
+		}
+		return result.Interface(), nil
+	}
+
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		result := reflect.New(t)
+		if err := result.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+			return nil, err
+		}
+		return result.Elem().Interface(), nil
+	}
+
+	return structure.ConvertToType(value, t)
+}
+
+// ByteSize is a number of bytes converted from strings such as "512", "10KB" or "10MiB".
+type ByteSize int64
+
+var byteSizeUnits = map[string]ByteSize{
+	"":    1,
+	"B":   1,
+	"KB":  1000,
+	"MB":  1000 * 1000,
+	"GB":  1000 * 1000 * 1000,
+	"TB":  1000 * 1000 * 1000 * 1000,
+	"K":   1 << 10,
+	"M":   1 << 20,
+	"G":   1 << 30,
+	"T":   1 << 40,
+	"KIB": 1 << 10,
+	"MIB": 1 << 20,
+	"GIB": 1 << 30,
+	"TIB": 1 << 40,
+}
+
+// ParseByteSize parses s, a number followed by an optional unit. The units KB, MB, GB and TB are powers of 1000,
+// KiB, MiB, GiB and TiB, or K, M, G and T, are powers of 1024. The units are case-insensitive.
+func ParseByteSize(s string) (ByteSize, error) {
+	s = strings.TrimSpace(s)
+
+	i := len(s)
+	for i > 0 && (s[i-1] < '0' || s[i-1] > '9') && s[i-1] != '.' {
+		i--
+	}
+
+	unit, ok := byteSizeUnits[strings.ToUpper(strings.TrimSpace(s[i:]))]
+	if !ok {
+		return 0, fmt.Errorf("invalid byte size unit: %s", s)
+	}
+
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid byte size: %s", s)
+	}
+
+	return ByteSize(n * float64(unit)), nil
+}
+
+func (b *ByteSize) UnmarshalText(text []byte) (err error) {
+	*b, err = ParseByteSize(string(text))
+	return
+}
@@ -0,0 +1,94 @@
+package factory
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+)
+
+type converterLevel int
+
+type converterValues struct {
+	Timeout time.Duration  `value:"1m30s"`
+	Start   time.Time      `value:"2024-05-06" layout:"2006-01-02"`
+	Stamp   time.Time      `value:"2024-05-06T07:08:09Z"`
+	URL     *url.URL       `value:"https://api.local/v1"`
+	IP      net.IP         `value:"10.0.0.1"`
+	Pattern *regexp.Regexp `value:"^a+$"`
+	Mode    os.FileMode    `value:"0644"`
+	Size    ByteSize       `value:"${'10' + 'MiB'}"`
+	Level   converterLevel `value:"debug"`
+	Text    unmarshaler    `value:"2s"`
+}
+
+type converterProps struct {
+	Start time.Time `env:"START" layout:"2006-01-02"`
+	URL   *url.URL  `env:"URL"`
+	Size  ByteSize  `env:"SIZE" default:"1KB"`
+}
+
+type converterInvalid struct {
+	URL *url.URL `value:"%zz"`
+}
+
+func TestConverter(t *testing.T) {
+	RegisterConverter(func(s string) (converterLevel, error) {
+		if s == "debug" {
+			return 1, nil
+		}
+		return 0, errors.New("unknown level")
+	})
+
+	c := NewContainer()
+	c.Prototype(new(converterValues))
+	assert.Nil(t, c.Validate())
+
+	var values *converterValues
+	c.New(&values)
+
+	assert.Equal(t, 90*time.Second, values.Timeout)
+	assert.Equal(t, time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC), values.Start)
+	assert.Equal(t, time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC), values.Stamp)
+	assert.Equal(t, "api.local", values.URL.Host)
+	assert.Equal(t, net.ParseIP("10.0.0.1"), values.IP)
+	assert.True(t, values.Pattern.MatchString("aaa"))
+	assert.Equal(t, os.FileMode(0644), values.Mode)
+	assert.Equal(t, ByteSize(10<<20), values.Size)
+	assert.Equal(t, converterLevel(1), values.Level)
+	assert.Equal(t, 2*time.Second, values.Text.Duration)
+
+	var invalid *converterInvalid
+	assert.ErrorContains(t, c.TryNew(&invalid), "invalid URL escape")
+}
+
+func TestConverterProperties(t *testing.T) {
+	t.Setenv("CONV_START", "2024-05-06")
+	t.Setenv("CONV_URL", "https://api.local")
+
+	c := NewContainer()
+	c.ConfigProperties(new(converterProps), "CONV_")
+
+	var props *converterProps
+	c.Find(&props)
+	assert.Equal(t, time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC), props.Start)
+	assert.Equal(t, "api.local", props.URL.Host)
+	assert.Equal(t, ByteSize(1000), props.Size)
+}
+
+func TestParseByteSize(t *testing.T) {
+	for s, expected := range map[string]ByteSize{"512": 512, "10KB": 10000, "10 kib": 10240, "1.5M": 3 << 19, "2GiB": 2 << 30, "1TB": 1e12} {
+		size, err := ParseByteSize(s)
+		assert.Nil(t, err, s)
+		assert.Equal(t, expected, size, s)
+	}
+
+	_, err := ParseByteSize("10XB")
+	assert.EqualError(t, err, "invalid byte size unit: 10XB")
+	_, err = ParseByteSize("MiB")
+	assert.EqualError(t, err, "invalid byte size: MiB")
+}
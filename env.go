@@ -2,8 +2,14 @@ package factory
 
 import (
 	"os"
+	"reflect"
 )
 
 func init() {
-	NamedSingleton[map[string]string]("env").SetInitFunc(func() any { return envToMap(os.Environ()) })
+	registerEnv(_context)
+	registerConfig(_context)
+}
+
+func registerEnv(c *factoryContext) {
+	_singletonWithType(c, reflect.TypeOf((*map[string]string)(nil))).Name("env").SetInitFunc(func() any { return envToMap(os.Environ()) })
 }
@@ -49,3 +49,75 @@ func appendErrors(errors []string, err error) []string {
 		return append(errors, e.Error())
 	}
 }
+
+var (
+	// ErrNotFound means no builder is registered for the type or name.
+	ErrNotFound = errors.New("not found")
+	// ErrAmbiguous means more than one builder can be used for the type.
+	ErrAmbiguous = errors.New("ambiguous")
+	// ErrCircular means the object depends on itself.
+	ErrCircular = errors.New("circular reference")
+	// ErrTimeout means the object isn't built in time.
+	ErrTimeout = errors.New("timeout")
+	// ErrInitFailed means the object is found, but building it failed.
+	ErrInitFailed = errors.New("init failed")
+	// ErrNoScope means a scoped object is got without a scope, see WithScope.
+	ErrNoScope = errors.New("no scope")
+	// ErrInvalidArgument means the ptr given to get an object into isn't a non-nil pointer to a struct pointer.
+	ErrInvalidArgument = errors.New("invalid argument")
+)
+
+// ResolveError is the error of getting an object from a container,
+// it matches its Kind with errors.Is, and unwraps to its cause.
+type ResolveError struct {
+	Kind error
+	Err  error
+	msg  string
+}
+
+func newResolveError(kind error, err error, format string, args ...any) *ResolveError {
+	return &ResolveError{
+		Kind: kind,
+		Err:  err,
+		msg:  fmt.Sprintf(format, args...),
+	}
+}
+
+func (e *ResolveError) Error() string {
+	return e.msg
+}
+
+func (e *ResolveError) Is(target error) bool {
+	return e.Kind == target
+}
+
+func (e *ResolveError) Unwrap() error {
+	return e.Err
+}
+
+// recoverError converts a recovered panic to an error,
+// the ones not raised by the container are reported as ErrInitFailed.
+func recoverError(r any) error {
+	err, ok := r.(error)
+	if !ok {
+		err = fmt.Errorf("%v", r)
+	}
+
+	var re *ResolveError
+	if errors.As(err, &re) {
+		return err
+	}
+
+	return newResolveError(ErrInitFailed, err, "%v", err)
+}
+
+func try(f func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recoverError(r)
+		}
+	}()
+
+	f()
+	return nil
+}
@@ -0,0 +1,148 @@
+package factory
+
+import (
+	"github.com/expgo/sync"
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/conf"
+	"github.com/expr-lang/expr/parser"
+	"github.com/expr-lang/expr/vm"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// compiledExpr is the program of an expr code, the identifiers resolved before running it,
+// and what the code does, which is checked by the ExprPolicy of the container.
+type compiledExpr struct {
+	program     *vm.Program
+	identifiers []string
+	funcs       []string // the called funcs, builtins or registered expr funcs
+	builtins    []string // the called builtins, each one once
+	nodes       int
+	methodCalls bool
+}
+
+// exprCache caches the compiled exprs by code and the builtins shadowed in it, nothing is evicted. The codes only come from
+// the tags and init params of the wired types and the When conditions, and the shadowed builtins from the names of the
+// registered expr funcs, so its size is bounded by the program, not by the containers created or the objects built.
+var exprCache = map[string]*compiledExpr{}
+var exprCacheLock = sync.NewRWMutex()
+
+// compileExpr compiles code once, the result is shared by all containers and safe to run concurrently.
+// The builtins in disabled are compiled as calls of the funcs in the env, such as the registered expr funcs with the same names.
+func compileExpr(code string, disabled ...string) (*compiledExpr, error) {
+	key := code
+	if len(disabled) > 0 {
+		key = strings.Join(disabled, ",") + "\x00" + code
+	}
+
+	exprCacheLock.RLock()
+	result, ok := exprCache[key]
+	exprCacheLock.RUnlock()
+
+	if ok {
+		return result, nil
+	}
+
+	config := conf.CreateNew()
+	options := []expr.Option{expr.Patch(deadlinePatcher{})}
+	for _, name := range disabled {
+		config.Disabled[name] = true
+		options = append(options, expr.DisableBuiltin(name))
+	}
+
+	tree, err := parser.ParseWithConfig(code, config)
+	if err != nil {
+		return nil, err
+	}
+
+	program, err := expr.Compile(code, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	analyzer := &exprAnalyzer{compiled: &compiledExpr{program: program}, seen: map[string]bool{}}
+	ast.Walk(&tree.Node, analyzer)
+
+	exprCacheLock.Lock()
+	defer exprCacheLock.Unlock()
+
+	if result, ok = exprCache[key]; !ok {
+		result = analyzer.compiled
+		exprCache[key] = result
+	}
+
+	return result, nil
+}
+
+// compileExpr compiles code like compileExpr, the expr funcs registered in c take precedence over the builtins with the same names,
+// but the expr vars don't.
+func (c *factoryContext) compileExpr(code string) (*compiledExpr, error) {
+	compiled, err := compileExpr(code)
+	if err != nil {
+		return nil, err
+	}
+
+	var shadowed []string
+	for _, name := range compiled.builtins {
+		// only the funcs, the vars never hide the builtins
+		if value, ok := c.lookupExprVar(name); ok && reflect.ValueOf(value).Kind() == reflect.Func {
+			shadowed = append(shadowed, name)
+		}
+	}
+
+	if len(shadowed) == 0 {
+		return compiled, nil
+	}
+
+	sort.Strings(shadowed)
+	return compileExpr(code, shadowed...)
+}
+
+// exprCheckIdentifier is the identifier of the func checking whether the evaluation is stopped, see exprRun.check.
+// It can't be written in exprs.
+const exprCheckIdentifier = "$check"
+
+// deadlinePatcher makes each iteration of the predicates, such as the ones of map() and filter(),
+// check whether the evaluation is stopped first, see ExprPolicy.MaxDuration.
+type deadlinePatcher struct{}
+
+func (deadlinePatcher) Visit(node *ast.Node) {
+	if closure, ok := (*node).(*ast.ClosureNode); ok {
+		closure.Node = &ast.CallNode{
+			Callee:    &ast.IdentifierNode{Value: exprCheckIdentifier},
+			Arguments: []ast.Node{closure.Node},
+		}
+	}
+}
+
+type exprAnalyzer struct {
+	compiled *compiledExpr
+	seen     map[string]bool
+}
+
+func (a *exprAnalyzer) Visit(node *ast.Node) {
+	a.compiled.nodes++
+
+	switch n := (*node).(type) {
+	case *ast.IdentifierNode:
+		// each identifier is resolved once
+		if !a.seen[n.Value] {
+			a.seen[n.Value] = true
+			a.compiled.identifiers = append(a.compiled.identifiers, n.Value)
+		}
+	case *ast.BuiltinNode:
+		a.compiled.funcs = append(a.compiled.funcs, n.Name)
+		if !contains(a.compiled.builtins, n.Name) {
+			a.compiled.builtins = append(a.compiled.builtins, n.Name)
+		}
+	case *ast.CallNode:
+		switch callee := n.Callee.(type) {
+		case *ast.IdentifierNode:
+			a.compiled.funcs = append(a.compiled.funcs, callee.Value)
+		case *ast.MemberNode:
+			a.compiled.methodCalls = true
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package factory
+
+import (
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/parser"
+	"github.com/stretchr/testify/assert"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+type exprCacheRequest struct {
+	A int    `value:"${1 + 1}"`
+	B int    `value:"${2 * 3}"`
+	C string `value:"${'a' + 'b'}"`
+	D bool   `value:"${1 < 2}"`
+	E int    `value:"${len('hello')}"`
+	F string `value:"${env.EXPR_CACHE_NAME ?? 'none'}"`
+	G int    `value:"${max(1, 5)}"`
+	H string `value:"${shout('x')}"`
+	I int    `value:"${10 % 3}"`
+	J string `value:"${'prefix-' + string(42)}"`
+}
+
+func TestCompileExpr(t *testing.T) {
+	first, err := compileExpr("env.HOME + shout(name) + name")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"env", "shout", "name"}, first.identifiers)
+
+	second, _ := compileExpr("env.HOME + shout(name) + name")
+	assert.Same(t, first, second)
+
+	_, err = compileExpr("1 +")
+	assert.NotNil(t, err)
+}
+
+func TestCompileExprConcurrent(t *testing.T) {
+	c := NewContainer()
+	c.RegisterExprFunc("shout", func(s string) string { return s + "!" })
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var r *exprCacheRequest
+			c.New(&r)
+			assert.Equal(t, "x!", r.H)
+			assert.Equal(t, 5, r.G)
+		}()
+	}
+	wg.Wait()
+}
+
+func BenchmarkNewValueFields(b *testing.B) {
+	c := NewContainer()
+	c.RegisterExprFunc("shout", func(s string) string { return s + "!" })
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var r *exprCacheRequest
+		c.New(&r)
+	}
+}
+
+// BenchmarkNewValueFieldsUncached is the baseline of BenchmarkNewValueFields, the exprs of the same struct are parsed,
+// walked and evaluated by expr.Eval every time, nothing is cached.
+func BenchmarkNewValueFieldsUncached(b *testing.B) {
+	vt := reflect.TypeOf(exprCacheRequest{})
+
+	var codes []string
+	for i := 0; i < vt.NumField(); i++ {
+		code, _ := getExpr(vt.Field(i).Tag.Get(TagValue.Name()))
+		codes = append(codes, code)
+	}
+
+	env := map[string]any{
+		"env":   map[string]string{},
+		"shout": func(s string) string { return s + "!" },
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for _, code := range codes {
+			tree, err := parser.Parse(code)
+			if err != nil {
+				b.Fatal(err)
+			}
+			ast.Walk(&tree.Node, &exprAnalyzer{compiled: &compiledExpr{}, seen: map[string]bool{}})
+
+			if _, err = expr.Eval(code, env); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package factory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ErrExprDenied means an expr breaks the ExprPolicy of its container.
+var ErrExprDenied = errors.New("expr denied")
+
+// ExprPolicy limits what the '${}' exprs evaluated in a container can do. The zero value allows everything
+// except calling methods, such as ${db.Ping()}. The per-object identifiers, self and the wired fields, are always allowed.
+// Besides, the ranges, arrays and maps built by an expr are limited by the memory budget of expr-lang, see vm.MemoryBudget.
+type ExprPolicy struct {
+	// AllowedIdentifiers are the named builders and expr vars exprs can use, nil allows all of them.
+	AllowedIdentifiers []string
+	// AllowedFuncs are the builtins and registered expr funcs exprs can call, nil allows all of them.
+	AllowedFuncs []string
+	// MaxNodes is the max number of AST nodes of an expr, 0 means no limit.
+	MaxNodes int
+	// MaxDuration is the max time of running an expr, resolving its identifiers isn't counted, 0 means no limit.
+	// An expr exceeding it is stopped and fails: each iteration of the predicates, such as the ones of map() and filter(),
+	// checks it first, and the registered expr funcs taking a context.Context as their first param get one cancelled then.
+	// A builtin or an expr func not taking a context.Context isn't interrupted, the expr stops after it returns.
+	MaxDuration time.Duration
+	// AllowMethodCalls allows exprs to call methods on the objects they use.
+	AllowMethodCalls bool
+}
+
+var defaultExprPolicy = &ExprPolicy{}
+
+// SetExprPolicy sets the policy of the exprs evaluated in the default container, see Container.SetExprPolicy.
+func SetExprPolicy(policy ExprPolicy) {
+	_context.setExprPolicy(policy)
+}
+
+// SetExprPolicy sets the policy of the exprs evaluated in c, the children of c without their own policies use it.
+// An expr breaking it fails with ErrExprDenied.
+func (c *Container) SetExprPolicy(policy ExprPolicy) {
+	c.context.setExprPolicy(policy)
+}
+
+func (c *factoryContext) setExprPolicy(policy ExprPolicy) {
+	c.exprEnvLock.Lock()
+	defer c.exprEnvLock.Unlock()
+
+	c.exprPolicy = &policy
+}
+
+// getExprPolicy returns the policy of c, or the one of its nearest parent.
+func (c *factoryContext) getExprPolicy() *ExprPolicy {
+	for fc := c; fc != nil; fc = fc.parent {
+		fc.exprEnvLock.RLock()
+		policy := fc.exprPolicy
+		fc.exprEnvLock.RUnlock()
+
+		if policy != nil {
+			return policy
+		}
+	}
+
+	return defaultExprPolicy
+}
+
+// check returns an error if compiled breaks p, locals are the per-object identifiers.
+func (p *ExprPolicy) check(compiled *compiledExpr, locals map[string]any) error {
+	if p.MaxNodes > 0 && compiled.nodes > p.MaxNodes {
+		return fmt.Errorf("%w: %d nodes exceed the max %d", ErrExprDenied, compiled.nodes, p.MaxNodes)
+	}
+
+	if compiled.methodCalls && !p.AllowMethodCalls {
+		return fmt.Errorf("%w: method calls aren't allowed", ErrExprDenied)
+	}
+
+	funcs := map[string]bool{}
+	for _, f := range compiled.funcs {
+		funcs[f] = true
+		if p.AllowedFuncs != nil && !contains(p.AllowedFuncs, f) {
+			return fmt.Errorf("%w: func %s isn't allowed", ErrExprDenied, f)
+		}
+	}
+
+	if p.AllowedIdentifiers != nil {
+		for _, identifier := range compiled.identifiers {
+			if _, ok := locals[identifier]; ok || funcs[identifier] {
+				continue
+			}
+			if !contains(p.AllowedIdentifiers, identifier) {
+				return fmt.Errorf("%w: identifier %s isn't allowed", ErrExprDenied, identifier)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkTemplatePolicy checks the exprs of segments against the policy of c without evaluating them.
+func (c *factoryContext) checkTemplatePolicy(ctx context.Context, locals map[string]any, segments []templateSegment) error {
+	policy := c.getExprPolicy()
+
+	for _, segment := range segments {
+		if !segment.isExpr {
+			continue
+		}
+
+		code := segment.code
+		if segment.hasDefault {
+			// only the root of the key is evaluated, see evalSegment
+			code = c.templateKeyRoot(ctx, locals, strings.Split(segment.key, ".")[0])
+		}
+
+		compiled, err := c.compileExpr(code)
+		if err != nil {
+			return err
+		}
+
+		if err = policy.check(compiled, locals); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// exprRun is an evaluation of an expr, it's stopped after the MaxDuration of its policy.
+type exprRun struct {
+	policy   *ExprPolicy
+	ctx      context.Context // cancelled when the evaluation is stopped
+	cancel   context.CancelFunc
+	exceeded atomic.Bool
+}
+
+func (p *ExprPolicy) newRun(ctx context.Context) *exprRun {
+	result := &exprRun{policy: p}
+	result.ctx, result.cancel = context.WithCancel(ctx)
+	return result
+}
+
+func (r *exprRun) err() error {
+	if r.exceeded.Load() {
+		return fmt.Errorf("%w: evaluation exceeds %s", ErrExprDenied, r.policy.MaxDuration)
+	}
+	return r.ctx.Err()
+}
+
+// check is called by each iteration of the predicates with the value of the iteration, see deadlinePatcher.
+func (r *exprRun) check(value any) (any, error) {
+	if err := r.err(); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// run runs program with env, it's stopped after MaxDuration.
+func (r *exprRun) run(program *vm.Program, env map[string]any) (any, error) {
+	env[exprCheckIdentifier] = r.check
+
+	if r.policy.MaxDuration > 0 {
+		timer := time.AfterFunc(r.policy.MaxDuration, func() {
+			r.exceeded.Store(true)
+			r.cancel()
+		})
+		defer timer.Stop()
+	}
+
+	result, err := expr.Run(program, env)
+	if exceeded := r.err(); exceeded != nil {
+		// stopped, or returned after it
+		return nil, exceeded
+	}
+
+	return result, err
+}
+
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// withExprContext returns fn with ctx bound as its first param if it's a func taking a context.Context,
+// exprs call it without the ctx. Otherwise fn itself is returned.
+func withExprContext(fn any, ctx context.Context) any {
+	ft := reflect.TypeOf(fn)
+	if ft == nil || ft.Kind() != reflect.Func || ft.NumIn() == 0 || ft.In(0) != contextType {
+		return fn
+	}
+
+	var in, out []reflect.Type
+	for i := 1; i < ft.NumIn(); i++ {
+		in = append(in, ft.In(i))
+	}
+	for i := 0; i < ft.NumOut(); i++ {
+		out = append(out, ft.Out(i))
+	}
+
+	fv := reflect.ValueOf(fn)
+	return reflect.MakeFunc(reflect.FuncOf(in, out, ft.IsVariadic()), func(args []reflect.Value) []reflect.Value {
+		args = append([]reflect.Value{reflect.ValueOf(ctx)}, args...)
+		if ft.IsVariadic() {
+			return fv.CallSlice(args)
+		}
+		return fv.Call(args)
+	}).Interface()
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
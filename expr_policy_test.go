@@ -0,0 +1,139 @@
+package factory
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type policyDB struct{}
+
+func (db *policyDB) Name() string { return "orders" }
+
+type policyMethod struct {
+	Name string `value:"${db.Name()}"`
+}
+
+type policyValues struct {
+	Base  string `value:"${upper('api')}"`
+	Path  string `value:"${self.Base + '/v1'}"`
+	Sleep string `value:"${sleep(1)}"`
+}
+
+type policyEnv struct {
+	Home string `value:"${env.HOME ?? ''}"`
+}
+
+func TestExprPolicyMethodCalls(t *testing.T) {
+	c := NewContainer()
+	c.NamedSingleton("db", new(policyDB))
+	c.Prototype(new(policyMethod))
+
+	var m *policyMethod
+	err := c.TryFind(&m)
+	assert.ErrorIs(t, err, ErrExprDenied)
+	assert.ErrorContains(t, err, "method calls aren't allowed on github.com/expgo/factory/policyMethod.Name")
+	assert.ErrorContains(t, c.Validate(), "*factory.policyMethod.Name: tag value value:${db.Name()} expr err: expr denied: method calls aren't allowed")
+
+	c.SetExprPolicy(ExprPolicy{AllowMethodCalls: true})
+	c.Find(&m)
+	assert.Equal(t, "orders", m.Name)
+}
+
+func TestExprPolicyAllowLists(t *testing.T) {
+	c := NewContainer()
+	c.RegisterExprFunc("sleep", func(ms int) string {
+		time.Sleep(time.Duration(ms) * 50 * time.Millisecond)
+		return "awake"
+	})
+	c.SetExprPolicy(ExprPolicy{AllowedIdentifiers: []string{}, AllowedFuncs: []string{"upper", "sleep"}})
+
+	var values *policyValues
+	c.New(&values)
+	assert.Equal(t, &policyValues{Base: "API", Path: "API/v1", Sleep: "awake"}, values)
+
+	var env *policyEnv
+	assert.ErrorContains(t, c.TryNew(&env), "expr denied: identifier env isn't allowed")
+
+	child := c.Child()
+	child.SetExprPolicy(ExprPolicy{AllowedFuncs: []string{"sleep"}})
+	assert.ErrorContains(t, child.TryNew(&values), "expr denied: func upper isn't allowed")
+}
+
+func TestExprPolicyLimits(t *testing.T) {
+	c := NewContainer()
+	c.RegisterExprFunc("sleep", func(ms int) string {
+		time.Sleep(time.Duration(ms) * 50 * time.Millisecond)
+		return "awake"
+	})
+
+	c.SetExprPolicy(ExprPolicy{MaxNodes: 3})
+	var values *policyValues
+	assert.ErrorContains(t, c.TryNew(&values), "expr denied: 5 nodes exceed the max 3")
+
+	c.SetExprPolicy(ExprPolicy{MaxDuration: 10 * time.Millisecond})
+	assert.ErrorContains(t, c.TryNew(&values), "expr denied: evaluation exceeds 10ms")
+}
+
+type policyLoop struct {
+	Count int `value:"${count(1..300, tick() > 0)}"`
+}
+
+type policyWait struct {
+	Value string `value:"${wait('done')}"`
+}
+
+func TestExprPolicyStopped(t *testing.T) {
+	var ticks int32
+
+	c := NewContainer()
+	c.RegisterExprFunc("tick", func() int {
+		atomic.AddInt32(&ticks, 1)
+		time.Sleep(time.Millisecond)
+		return 1
+	})
+	c.RegisterExprFunc("wait", func(ctx context.Context, value string) (string, error) {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(300 * time.Millisecond):
+			return value, nil
+		}
+	})
+	c.Prototype(new(policyLoop))
+	c.Prototype(new(policyWait))
+
+	var loop *policyLoop
+	c.New(&loop)
+	assert.Equal(t, 300, loop.Count)
+
+	c.SetExprPolicy(ExprPolicy{MaxDuration: 20 * time.Millisecond})
+	atomic.StoreInt32(&ticks, 0)
+	assert.ErrorContains(t, c.TryNew(&loop), "expr denied: evaluation exceeds 20ms")
+	// stopped in the middle of the loop
+	assert.Less(t, atomic.LoadInt32(&ticks), int32(150))
+
+	var wait *policyWait
+	start := time.Now()
+	assert.ErrorContains(t, c.TryNew(&wait), "expr denied: evaluation exceeds 20ms")
+	assert.Less(t, time.Since(start), 200*time.Millisecond)
+
+	c.SetExprPolicy(ExprPolicy{MaxDuration: 2 * time.Second})
+	c.New(&wait)
+	assert.Equal(t, "done", wait.Value)
+}
+
+type policyDefault struct {
+	Host string `value:"${db.host:localhost}"`
+}
+
+func TestExprPolicyDefault(t *testing.T) {
+	c := NewContainer()
+	c.NamedSingleton("db", new(policyDB))
+	c.Prototype(new(policyDefault))
+	c.SetExprPolicy(ExprPolicy{AllowedIdentifiers: []string{"config"}})
+
+	assert.ErrorContains(t, c.Validate(), "expr denied: identifier db isn't allowed")
+}
@@ -0,0 +1,61 @@
+package factory
+
+import (
+	"context"
+	"reflect"
+	"unsafe"
+)
+
+// SelfIdentifier is the identifier of the object being wired in its '${}' exprs, such as ${self.BaseURL + '/v1'}.
+const SelfIdentifier = "self"
+
+// exprScope holds self and its fields already wired, they're visible by name to the exprs of self's other fields.
+type exprScope struct {
+	self   any
+	locals map[string]any
+}
+
+// withExprScope returns a ctx holding a new scope of self.
+func withExprScope(ctx context.Context, self any) (context.Context, *exprScope) {
+	scope := &exprScope{self: self, locals: map[string]any{SelfIdentifier: self}}
+	return context.WithValue(ctx, ExprScopeKey, scope), scope
+}
+
+// wired records the direct field of self after it's wired.
+func (s *exprScope) wired(fieldValue reflect.Value, structField reflect.StructField, rootValues []reflect.Value) {
+	if len(rootValues) != 1 || structField.Name == SelfIdentifier {
+		return
+	}
+
+	if !fieldValue.CanInterface() {
+		if !fieldValue.CanAddr() {
+			return
+		}
+		fieldValue = reflect.NewAt(fieldValue.Type(), unsafe.Pointer(fieldValue.UnsafeAddr())).Elem()
+	}
+
+	s.locals[structField.Name] = fieldValue.Interface()
+}
+
+// set records value as the direct field of self, before the field is set.
+func (s *exprScope) set(structField reflect.StructField, rootValues []reflect.Value, value any) {
+	if len(rootValues) != 1 || structField.Name == SelfIdentifier {
+		return
+	}
+
+	s.locals[structField.Name] = value
+}
+
+// exprLocals returns the per-object identifiers of the exprs evaluated for self:
+// self itself, and its fields already wired in the scope held by ctx.
+func exprLocals(ctx context.Context, self any) map[string]any {
+	if self == nil {
+		return nil
+	}
+
+	if scope, ok := ctx.Value(ExprScopeKey).(*exprScope); ok && scope.self == self {
+		return scope.locals
+	}
+
+	return map[string]any{SelfIdentifier: self}
+}
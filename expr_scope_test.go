@@ -0,0 +1,55 @@
+package factory
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type scopeClient struct {
+	BaseURL  string `value:"https://api.local"`
+	Endpoint string `value:"${self.BaseURL + '/v1'}"`
+	Users    string `value:"${Endpoint}/users"`
+	Timeout  int    `value:"${self.Retries * 10}"`
+	Retries  int
+	Name     string `value:"${self.name:client}"`
+}
+
+type scopeOrder struct {
+	Later string `value:"${Next ?? 'unset'}"`
+	Next  string `value:"next"`
+}
+
+func TestExprScope(t *testing.T) {
+	c := NewContainer()
+	c.Singleton(&scopeClient{Retries: 3})
+	assert.Nil(t, c.Validate())
+
+	client := &scopeClient{Retries: 3}
+	assert.Nil(t, c.AutoWire(client))
+	assert.Equal(t, &scopeClient{
+		BaseURL:  "https://api.local",
+		Endpoint: "https://api.local/v1",
+		Users:    "https://api.local/v1/users",
+		Timeout:  30,
+		Retries:  3,
+		Name:     "client",
+	}, client)
+
+	// the per-object identifiers aren't cached in the shared env
+	c.context.exprEnvLock.RLock()
+	_, selfCached := c.context.exprEnvMap[SelfIdentifier]
+	_, endpointCached := c.context.exprEnvMap["Endpoint"]
+	c.context.exprEnvLock.RUnlock()
+	assert.False(t, selfCached)
+	assert.False(t, endpointCached)
+}
+
+func TestExprScopeOrder(t *testing.T) {
+	c := NewContainer()
+	c.Singleton(new(scopeOrder))
+
+	// the fields wired later aren't visible, Next is looked up as a named builder
+	var order *scopeOrder
+	assert.ErrorIs(t, c.TryFind(&order), ErrNotFound)
+	assert.ErrorContains(t, c.Validate(), "Named builder Next not found.")
+}
@@ -0,0 +1,95 @@
+package factory
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// RegisterExprFunc registers fn as a function of the '${}' expressions of the default container, see Container.RegisterExprFunc.
+func RegisterExprFunc(name string, fn any) {
+	_context.registerExprFunc(name, fn)
+}
+
+// RegisterExprVar registers value as a variable of the '${}' expressions of the default container, see Container.RegisterExprVar.
+func RegisterExprVar(name string, value any) {
+	_context.registerExprVar(name, value)
+}
+
+// RegisterExprFunc registers fn as a function of the '${}' expressions evaluated in c, such as ${duration(env.TIMEOUT ?? '5s')}.
+// fn may return an error as its last result, which fails the evaluation. It takes precedence over the builtin with the same name,
+// and the name can't be used by a named builder of c or its parents.
+func (c *Container) RegisterExprFunc(name string, fn any) {
+	c.context.registerExprFunc(name, fn)
+}
+
+// RegisterExprVar registers value as a variable of the '${}' expressions evaluated in c. The name can't be used by a named builder
+// of c or its parents. A var doesn't hide the builtin with the same name, only a func does, see RegisterExprFunc.
+func (c *Container) RegisterExprVar(name string, value any) {
+	c.context.registerExprVar(name, value)
+}
+
+func (c *factoryContext) registerExprFunc(name string, fn any) {
+	if fn == nil || reflect.TypeOf(fn).Kind() != reflect.Func {
+		panic(fmt.Errorf("expr func %s must be a func, get %T", name, fn))
+	}
+
+	c.registerExprVar(name, fn)
+}
+
+func (c *factoryContext) registerExprVar(name string, value any) {
+	name = strings.TrimSpace(name)
+	if len(name) == 0 {
+		panic("name must not be empty")
+	}
+
+	c.flushRegistrations()
+	if c.parentNamed(name) {
+		panic(fmt.Errorf("Expr var %s collides with the named builder", name))
+	}
+
+	c.namedMapLock.Lock()
+	defer c.namedMapLock.Unlock()
+
+	if _, ok := c.namedMap[name]; ok {
+		panic(fmt.Errorf("Expr var %s collides with the named builder", name))
+	}
+
+	if _, ok := c.exprVars[name]; ok {
+		panic(fmt.Errorf("Expr var allready exist: %s", name))
+	}
+
+	c.exprVars[name] = value
+}
+
+// parentNamed reports whether name is used by a named builder of the parents of c.
+func (c *factoryContext) parentNamed(name string) bool {
+	for fc := c.parent; fc != nil; fc = fc.parent {
+		fc.flushRegistrations()
+
+		fc.namedMapLock.RLock()
+		_, ok := fc.namedMap[name]
+		fc.namedMapLock.RUnlock()
+
+		if ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lookupExprVar gets the expr func or var registered with name, the ones of c hide the ones of its parents.
+func (c *factoryContext) lookupExprVar(name string) (any, bool) {
+	for fc := c; fc != nil; fc = fc.parent {
+		fc.namedMapLock.RLock()
+		value, ok := fc.exprVars[name]
+		fc.namedMapLock.RUnlock()
+
+		if ok {
+			return value, true
+		}
+	}
+
+	return nil, false
+}
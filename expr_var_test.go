@@ -0,0 +1,103 @@
+package factory
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+type exprVarServer struct {
+	Timeout  time.Duration `value:"${duration(env.EXPR_VAR_TIMEOUT ?? '5s')}"`
+	Hostname string        `value:"${hostname()}"`
+	Region   string        `value:"${upper(region)}"`
+}
+
+func newExprVarContainer() *Container {
+	c := NewContainer()
+	c.RegisterExprFunc("duration", time.ParseDuration)
+	c.RegisterExprFunc("hostname", os.Hostname)
+	c.RegisterExprFunc("upper", strings.ToUpper)
+	c.RegisterExprVar("region", "eu")
+	c.Singleton(new(exprVarServer))
+	return c
+}
+
+func TestExprVar(t *testing.T) {
+	t.Setenv("EXPR_VAR_TIMEOUT", "2m")
+
+	c := newExprVarContainer()
+	assert.Nil(t, c.Validate())
+
+	var server *exprVarServer
+	c.Find(&server)
+
+	hostname, _ := os.Hostname()
+	assert.Equal(t, &exprVarServer{Timeout: 2 * time.Minute, Hostname: hostname, Region: "EU"}, server)
+
+	// the child's vars hide the parent's
+	child := c.Child()
+	child.RegisterExprVar("region", "us")
+	child.Prototype(new(exprVarServer))
+	child.New(&server)
+	assert.Equal(t, "US", server.Region)
+}
+
+func TestExprVarError(t *testing.T) {
+	t.Setenv("EXPR_VAR_TIMEOUT", "abc")
+
+	c := newExprVarContainer()
+
+	var server *exprVarServer
+	assert.ErrorContains(t, c.TryFind(&server), `time: invalid duration "abc"`)
+
+	assert.PanicsWithError(t, "expr func now must be a func, get string", func() { c.RegisterExprFunc("now", "now") })
+	assert.PanicsWithError(t, "Expr var env collides with the named builder", func() { c.RegisterExprVar("env", "x") })
+	assert.PanicsWithError(t, "Expr var allready exist: region", func() { c.RegisterExprVar("region", "us") })
+	assert.PanicsWithError(t, "Named builder region collides with the expr var", func() { c.NamedSingleton("region", new(exprVarServer)) })
+
+	// the names of the parents are checked too
+	child := c.Child()
+	assert.PanicsWithError(t, "Expr var env collides with the named builder", func() { child.RegisterExprVar("env", "x") })
+	assert.PanicsWithError(t, "Named builder region collides with the expr var", func() { child.NamedSingleton("region", new(exprVarServer)) })
+}
+
+type exprVarBuiltin struct {
+	Timeout string `value:"${duration('5s')}"`
+	Name    string `value:"${upper('a') + lower('B')}"`
+}
+
+func TestExprVarBuiltin(t *testing.T) {
+	c := NewContainer()
+	c.RegisterExprFunc("duration", func(s string) string { return "duration:" + s })
+	c.Prototype(new(exprVarBuiltin))
+	assert.Nil(t, c.Validate())
+
+	// the registered func is called instead of the builtin, the other builtins are kept
+	var builtin *exprVarBuiltin
+	c.New(&builtin)
+	assert.Equal(t, &exprVarBuiltin{Timeout: "duration:5s", Name: "Ab"}, builtin)
+
+	// the program compiled for c isn't used by the containers without the func
+	other := NewContainer()
+	other.Prototype(new(exprVarBuiltin))
+	assert.ErrorContains(t, other.TryNew(&builtin), "no mapper found for type time.Duration to string")
+}
+
+type exprVarLen struct {
+	Len int `value:"${len('ab') + size}"`
+}
+
+func TestExprVarNamedLikeBuiltin(t *testing.T) {
+	// only the funcs hide the builtins
+	c := NewContainer()
+	c.RegisterExprVar("len", 5)
+	c.RegisterExprVar("size", 1)
+	c.Prototype(new(exprVarLen))
+	assert.Nil(t, c.Validate())
+
+	var l *exprVarLen
+	c.New(&l)
+	assert.Equal(t, 3, l.Len)
+}
@@ -4,15 +4,11 @@ import (
 	"context"
 	"fmt"
 	"github.com/expgo/structure"
-	"github.com/expgo/sync"
 	"reflect"
 )
 
 const NewMethodName = "New"
 
-var factories = make(map[reflect.Type]*_factory)
-var factoriesLock = sync.NewRWMutex()
-
 type _factory struct {
 	factory     any
 	factoryType reflect.Type
@@ -80,6 +76,10 @@ func Factory[T any](f any) *_factory {
 }
 
 func FactoryWithType(vt reflect.Type, f any) *_factory {
+	return _context.factoryWithType(vt, f)
+}
+
+func (c *factoryContext) factoryWithType(vt reflect.Type, f any) *_factory {
 	if vt.Elem().Kind() == reflect.Interface {
 		vt = vt.Elem()
 	}
@@ -91,19 +91,34 @@ func FactoryWithType(vt reflect.Type, f any) *_factory {
 		methodName:  NewMethodName,
 	}
 
-	factoriesLock.RLock()
-	_, loaded := factories[vt]
-	factoriesLock.RUnlock()
+	c.setFactory(vt, fac)
+
+	return fac
+}
+
+func (c *factoryContext) setFactory(vt reflect.Type, fac *_factory) {
+	c.factoriesLock.Lock()
+	defer c.factoriesLock.Unlock()
 
-	if loaded {
+	if _, loaded := c.factories[vt]; loaded {
 		panic(fmt.Errorf("factory already exist: %s", vt.String()))
 	}
 
-	factoriesLock.Lock()
-	factories[vt] = fac
-	factoriesLock.Unlock()
+	c.factories[vt] = fac
+}
 
-	return fac
+func (c *factoryContext) getFactory(vt reflect.Type) (*_factory, bool) {
+	for fc := c; fc != nil; fc = fc.parent {
+		fc.factoriesLock.RLock()
+		f, loaded := fc.factories[vt]
+		fc.factoriesLock.RUnlock()
+
+		if loaded {
+			return f, true
+		}
+	}
+
+	return nil, false
 }
 
 func callFactory(ctx context.Context, f *_factory, self any, fieldValue reflect.Value, structField reflect.StructField, newParams []string) error {
@@ -117,7 +132,7 @@ func callFactory(ctx context.Context, f *_factory, self any, fieldValue reflect.
 		funcValue := reflect.ValueOf(f.factory)
 		funcType := funcValue.Type()
 
-		params, err := _getMethodParams(ctx, self, funcType, newParams, funcType.Name())
+		params, err := _getMethodParams(ctx, self, funcType, newParams, funcName(f.factory))
 		if err != nil {
 			panic(fmt.Errorf("factory func %s error: %v", structField.Type.String(), err))
 		}
@@ -5,75 +5,167 @@ import (
 	"fmt"
 	"github.com/expgo/structure"
 	"github.com/expgo/sync"
-	"github.com/expr-lang/expr"
-	"github.com/expr-lang/expr/ast"
-	"github.com/expr-lang/expr/parser"
 	"reflect"
+	"sort"
+	"strings"
 	"time"
 )
 
-var _context = &factoryContext{
-	typedMap:     make(map[reflect.Type]*contextCachedItem),
-	typedMapLock: sync.NewRWMutex(),
-	namedMap:     make(map[string]*contextCachedItem),
-	namedMapLock: sync.NewRWMutex(),
-	exprEnvMap:   make(map[string]any),
-	exprEnvLock:  sync.NewRWMutex(),
-}
+var _context = newFactoryContext()
 
 type factoryContext struct {
-	typedMap     map[reflect.Type]*contextCachedItem // package:name -> must builder
-	typedMapLock sync.RWMutex
-	namedMap     map[string]*contextCachedItem // name -> must builder
-	namedMapLock sync.RWMutex
-	exprEnvMap   map[string]any
-	exprEnvLock  sync.RWMutex
+	typedMap       map[reflect.Type]*contextCachedItem // package:name -> must builder
+	typedMapLock   sync.RWMutex
+	namedMap       map[string]*contextCachedItem // name -> must builder
+	namedMapLock   sync.RWMutex
+	exprVars       map[string]any // name -> registered expr func or var, guarded by namedMapLock
+	exprEnvMap     map[string]any
+	exprEnvLock    sync.RWMutex
+	exprPolicy     *ExprPolicy // guarded by exprEnvLock, nil uses the parent's
+	factories      map[reflect.Type]*_factory
+	factoriesLock  sync.RWMutex
+	pools          *poolCache
+	lifecycle      *lifecycle
+	config         *configResolver
+	profiles       []string        // the active ones, Opts.Profiles and the ones activated by ActivateProfiles
+	profileEntries []*registration // the registrations depending on profiles
+	registrations  []*registration // the pending ones, see flushRegistrations
+	profileLock    sync.Mutex      // guards the profiles and registrations
+	parent         *factoryContext
+	children       []*factoryContext
+	childrenLock   sync.Mutex
+
+	pendingRegistrations int32
+}
+
+func newFactoryContext() *factoryContext {
+	return &factoryContext{
+		typedMap:      make(map[reflect.Type]*contextCachedItem),
+		typedMapLock:  sync.NewRWMutex(),
+		namedMap:      make(map[string]*contextCachedItem),
+		namedMapLock:  sync.NewRWMutex(),
+		exprVars:      make(map[string]any),
+		exprEnvMap:    make(map[string]any),
+		exprEnvLock:   sync.NewRWMutex(),
+		factories:     make(map[reflect.Type]*_factory),
+		factoriesLock: sync.NewRWMutex(),
+		pools:         newPoolCache(),
+		lifecycle:     newLifecycle(),
+		config:        newConfigResolver(),
+		profiles:      append([]string(nil), Opts.Profiles...),
+		profileLock:   sync.NewMutex(),
+		childrenLock:  sync.NewMutex(),
+	}
+}
+
+func (c *factoryContext) child() *factoryContext {
+	result := newFactoryContext()
+	result.parent = c
+	result.config = c.config.child()
+
+	c.childrenLock.Lock()
+	c.children = append(c.children, result)
+	c.childrenLock.Unlock()
+
+	return result
 }
 
+func (c *factoryContext) getChildren() []*factoryContext {
+	c.childrenLock.Lock()
+	defer c.childrenLock.Unlock()
+
+	return append([]*factoryContext(nil), c.children...)
+}
+
+type itemKind string
+
+const (
+	itemKindSingleton itemKind = "singleton"
+	itemKindInterface itemKind = "interface"
+	itemKindScoped    itemKind = "scoped"
+	itemKindPrototype itemKind = "prototype"
+	itemKindFactory   itemKind = "factory"
+	itemKindPool      itemKind = "pool"
+)
+
 type contextCachedItem struct {
-	_type  reflect.Type
-	getter func(ctx context.Context) any
+	context    *factoryContext // the container it's registered in
+	_type      reflect.Type
+	getter     func(ctx context.Context) any
+	kind       itemKind
+	lazy       bool
+	primary    bool
+	order      int
+	option     *Option
+	conditions *itemConditions
+	checkValid func() // panics if nothing can be built, checked by Validate
+	// the '${}' fields are re-evaluated when the config is reloaded
+	refreshable bool
 }
 
 type exprContext struct {
-	ctx context.Context
+	ctx     context.Context
+	run     *exprRun // the registered funcs taking a context.Context get its ctx
+	context *factoryContext
+	locals  map[string]any // the per-object identifiers, never cached in exprEnvMap
+	env     map[string]any // the identifiers of the evaluated expr
 }
 
-func (c *exprContext) Visit(node *ast.Node) {
-	if s, ok := (*node).(*ast.IdentifierNode); ok {
-		_, ok = c.getValue(s.String())
-		if !ok {
-			value := _context.getByNamePanic(c.ctx, s.String(), nil)
-			c.setValue(s.String(), value)
-		}
+// exprValuer is implemented by the named builders whose objects are seen by exprs as another value,
+// which is got on each evaluation, such as the current values of the reloadable config.
+type exprValuer interface {
+	exprValue() any
+}
+
+// resolve puts the value of the identifier name into the env, it's a local, a registered expr var or a named builder.
+func (c *exprContext) resolve(name string) {
+	if value, ok := c.locals[name]; ok {
+		c.env[name] = value
+		return
+	}
+
+	if value, ok := c.context.lookupExprVar(name); ok {
+		c.env[name] = withExprContext(value, c.run.ctx)
+		return
 	}
+
+	value, ok := c.getValue(name)
+	if !ok {
+		value = c.context.getByNamePanic(c.ctx, name, nil)
+		c.setValue(name, value)
+	}
+
+	if valuer, ok := value.(exprValuer); ok {
+		value = valuer.exprValue()
+	}
+	c.env[name] = value
 }
 
 func (c *exprContext) getValue(name string) (any, bool) {
-	_context.exprEnvLock.RLock()
-	defer _context.exprEnvLock.RUnlock()
+	c.context.exprEnvLock.RLock()
+	defer c.context.exprEnvLock.RUnlock()
 
-	value, ok := _context.exprEnvMap[name]
+	value, ok := c.context.exprEnvMap[name]
 	return value, ok
 }
 
 func (c *exprContext) setValue(name string, value any) {
-	_context.exprEnvLock.Lock()
-	defer _context.exprEnvLock.Unlock()
+	c.context.exprEnvLock.Lock()
+	defer c.context.exprEnvLock.Unlock()
 
-	_context.exprEnvMap[name] = value
+	c.context.exprEnvMap[name] = value
 }
 
 func Find[T any]() *T {
-	return findTimeout(reflect.TypeOf((*T)(nil)), Opts.Timeout).(*T)
+	return _context.findTimeout(reflect.TypeOf((*T)(nil)), Opts.Timeout).(*T)
 }
 
 func FindTimeout[T any](timeout time.Duration) *T {
-	return findTimeout(reflect.TypeOf((*T)(nil)), timeout).(*T)
+	return _context.findTimeout(reflect.TypeOf((*T)(nil)), timeout).(*T)
 }
 
-func findTimeout(vt reflect.Type, timeout time.Duration) any {
-	result := _context.getByType(getTimeoutContext(timeout), vt)
+func (c *factoryContext) findTimeout(vt reflect.Type, timeout time.Duration) any {
+	result := c.getByType(c.timeoutContext(timeout), vt)
 
 	resultType := reflect.TypeOf(result)
 	if resultType.Kind() == reflect.Ptr && resultType.ConvertibleTo(vt) {
@@ -85,15 +177,15 @@ func findTimeout(vt reflect.Type, timeout time.Duration) any {
 }
 
 func FindByName[T any](name string) *T {
-	return findByNameTimeout(reflect.TypeOf((*T)(nil)), name, Opts.Timeout).(*T)
+	return _context.findByNameTimeout(reflect.TypeOf((*T)(nil)), name, Opts.Timeout).(*T)
 }
 
 func FindByNameTimeout[T any](name string, timeout time.Duration) *T {
-	return findByNameTimeout(reflect.TypeOf((*T)(nil)), name, timeout).(*T)
+	return _context.findByNameTimeout(reflect.TypeOf((*T)(nil)), name, timeout).(*T)
 }
 
-func findByNameTimeout(vt reflect.Type, name string, timeout time.Duration) any {
-	result := _context.getByNamePanic(getTimeoutContext(timeout), name, vt)
+func (c *factoryContext) findByNameTimeout(vt reflect.Type, name string, timeout time.Duration) any {
+	result := c.getByNamePanic(c.timeoutContext(timeout), name, vt)
 
 	resultType := reflect.TypeOf(result)
 	if resultType.Kind() == reflect.Ptr && resultType.ConvertibleTo(vt) {
@@ -109,7 +201,7 @@ func Range[T any](rangeFunc func(any) bool) {
 }
 
 func RangeTimeout[T any](rangeFunc func(any) bool, timeout time.Duration) {
-	rangeContext[T](rangeFunc, getTimeoutContext(timeout))
+	rangeContext[T](rangeFunc, _context.timeoutContext(timeout))
 }
 
 func rangeContext[T any](rangeFunc func(any) bool, ctx context.Context) {
@@ -121,11 +213,14 @@ func rangeContext[T any](rangeFunc func(any) bool, ctx context.Context) {
 		panic("Range only range type and interface")
 	}
 
-	_context.typedMapLock.RLock()
-	clonedMap := structure.CloneMap(_context.typedMap)
-	_context.typedMapLock.RUnlock()
+	clonedMap := getContextContainer(ctx).cloneTypedMap(ctx)
+	inScope := getContextScope(ctx) != nil
 
 	for k, v := range clonedMap {
+		if v.kind == itemKindScoped && !inScope {
+			continue
+		}
+
 		if k.ConvertibleTo(vt) {
 			rangeFunc(v.getter(ctx))
 		}
@@ -195,15 +290,49 @@ func (c *factoryContext) getByNameOrType(ctx context.Context, name string, vt re
 }
 
 func (c *factoryContext) getByType(ctx context.Context, vt reflect.Type) any {
+	// 先在自身查找，找不到再到父容器中查找
+	for fc := c; fc != nil; fc = fc.parent {
+		if mb, ok := fc.getItemByType(ctx, vt); ok {
+			ctx = pushGetter(ctx, mb)
+			defer popGetter(ctx)
+
+			return mb.getter(ctx)
+		}
+	}
+
+	svt := vt
+	if svt.Kind() == reflect.Ptr {
+		svt = svt.Elem()
+	}
+
+	panic(newResolveError(ErrNotFound, nil, "use type to get Getter, %s:%s not found", svt.PkgPath(), svt.Name()))
+}
+
+// lookupItemByType looks up the builder of vt like getByType, but doesn't get the object.
+func (c *factoryContext) lookupItemByType(ctx context.Context, vt reflect.Type) (result *contextCachedItem, err error) {
+	err = try(func() {
+		for fc := c; fc != nil && result == nil; fc = fc.parent {
+			result, _ = fc.getItemByType(ctx, vt)
+		}
+	})
+
+	if err == nil && result == nil {
+		err = newResolveError(ErrNotFound, nil, "%s not found", vt.String())
+	}
+
+	return
+}
+
+// getItemByType only looks up the builders registered in c itself, not in its parent.
+func (c *factoryContext) getItemByType(ctx context.Context, vt reflect.Type) (*contextCachedItem, bool) {
+	c.flushRegistrations()
+
 	c.typedMapLock.RLock()
 	mb, ok := c.typedMap[vt]
 	c.typedMapLock.RUnlock()
 
-	if ok {
-		ctx = pushGetter(ctx, mb)
-		defer popGetter(ctx)
-
-		return mb.getter(ctx)
+	if ok && mb.enabled(ctx) {
+		return mb, true
 	}
 
 	if vt.Kind() == reflect.Interface {
@@ -214,7 +343,7 @@ func (c *factoryContext) getByType(ctx context.Context, vt reflect.Type) any {
 
 		convertibleMap := make(map[reflect.Type]*contextCachedItem)
 		for k, v := range clonedMap {
-			if k.ConvertibleTo(vt) {
+			if k.ConvertibleTo(vt) && v.enabled(ctx) {
 				convertibleMap[k] = v
 			}
 		}
@@ -222,35 +351,39 @@ func (c *factoryContext) getByType(ctx context.Context, vt reflect.Type) any {
 		convertibleMapSize := len(convertibleMap)
 
 		if convertibleMapSize > 1 {
-			panic(fmt.Errorf("Multiple default builders found for type: %v, please use named singleton", vt))
-		}
-
-		if convertibleMapSize == 1 {
+			// 多个实现时，使用标记为primary的那个
+			var primaries []*contextCachedItem
 			for _, v := range convertibleMap {
-				mb = v
-				ok = true
-				break
+				if v.primary {
+					primaries = append(primaries, v)
+				}
 			}
 
-			if ok {
-				ctx = pushGetter(ctx, mb)
-				defer popGetter(ctx)
-
-				return mb.getter(ctx)
+			switch len(primaries) {
+			case 0:
+				panic(newResolveError(ErrAmbiguous, nil, "Multiple default builders found for type: %v, please use named singleton", vt))
+			case 1:
+				return primaries[0], true
+			default:
+				panic(newResolveError(ErrAmbiguous, nil, "Multiple primary builders found for type: %v", vt))
 			}
 		}
-	}
 
-	svt := vt
-	if svt.Kind() == reflect.Ptr {
-		svt = svt.Elem()
+		for _, v := range convertibleMap {
+			return v, true
+		}
 	}
 
-	panic(fmt.Errorf("use type to get Getter, %s:%s not found", svt.PkgPath(), svt.Name()))
-
+	return nil, false
 }
 
+// setByType registers cci by vt, after the pending registrations of c.
 func (c *factoryContext) setByType(vt reflect.Type, cci *contextCachedItem) {
+	c.flushRegistrations()
+	c.putByType(vt, cci)
+}
+
+func (c *factoryContext) putByType(vt reflect.Type, cci *contextCachedItem) {
 	c.typedMapLock.Lock()
 	defer c.typedMapLock.Unlock()
 
@@ -271,11 +404,7 @@ func (c *factoryContext) getByNamePanic(ctx context.Context, name string, vt ref
 }
 
 func (c *factoryContext) getByName(ctx context.Context, name string, vt reflect.Type) (any, error) {
-	c.namedMapLock.RLock()
-	mb, ok := c.namedMap[name]
-	c.namedMapLock.RUnlock()
-
-	if ok {
+	if mb, ok := c.getItemByName(ctx, name); ok {
 		ctx = pushGetter(ctx, mb)
 		defer popGetter(ctx)
 
@@ -290,10 +419,231 @@ func (c *factoryContext) getByName(ctx context.Context, name string, vt reflect.
 		}
 	}
 
-	return nil, fmt.Errorf("Named builder %s not found.", name)
+	return nil, newResolveError(ErrNotFound, nil, "Named builder %s not found.", name)
+}
+
+func (c *factoryContext) timeoutContext(timeout time.Duration) context.Context {
+	return withContainer(getTimeoutContext(timeout), c)
+}
+
+// getAll gets all the objects can be assigned to the element type of the slice type vt.
+// The scoped builders are skipped if ctx doesn't hold a scope.
+func (c *factoryContext) getAll(ctx context.Context, vt reflect.Type) reflect.Value {
+	items := c.getAllItems(ctx, vt.Elem())
+	inScope := getContextScope(ctx) != nil
+
+	result := reflect.MakeSlice(vt, 0, len(items))
+	for _, item := range items {
+		if item.kind == itemKindScoped && !inScope {
+			continue
+		}
+
+		func() {
+			ctx := pushGetter(ctx, item)
+			defer popGetter(ctx)
+
+			result = reflect.Append(result, reflect.ValueOf(item.getter(ctx)))
+		}()
+	}
+
+	return result
 }
 
+// getAllItems returns the builders visible from c which can be assigned to vt,
+// ordered by their order, then by their names, the type is used as the name of the unnamed ones.
+func (c *factoryContext) getAllItems(ctx context.Context, vt reflect.Type) []*contextCachedItem {
+	names := make(map[*contextCachedItem]string)
+	seenTypes := make(map[reflect.Type]bool)
+	seenNames := make(map[string]bool)
+
+	for fc := c; fc != nil; fc = fc.parent {
+		fc.flushRegistrations()
+
+		fc.typedMapLock.RLock()
+		for k, v := range fc.typedMap {
+			if !seenTypes[k] {
+				seenTypes[k] = true
+				if _, ok := names[v]; !ok && k.AssignableTo(vt) {
+					names[v] = k.String()
+				}
+			}
+		}
+		fc.typedMapLock.RUnlock()
+
+		fc.namedMapLock.RLock()
+		for k, v := range fc.namedMap {
+			if !seenNames[k] {
+				seenNames[k] = true
+				if v._type.AssignableTo(vt) {
+					names[v] = k
+				}
+			}
+		}
+		fc.namedMapLock.RUnlock()
+	}
+
+	var result []*contextCachedItem
+	for cci := range names {
+		if cci.enabled(ctx) {
+			result = append(result, cci)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].order != result[j].order {
+			return result[i].order < result[j].order
+		}
+		return names[result[i]] < names[result[j]]
+	})
+
+	return result
+}
+
+// getNamedMap gets the named objects can be assigned to the element type of the map type vt, keyed by their names,
+// only the names start with prefix are used. The scoped builders are skipped if ctx doesn't hold a scope.
+func (c *factoryContext) getNamedMap(ctx context.Context, vt reflect.Type, prefix string) reflect.Value {
+	items := c.getNamedItems(ctx, vt.Elem(), prefix)
+	inScope := getContextScope(ctx) != nil
+
+	result := reflect.MakeMapWithSize(vt, len(items))
+	for name, item := range items {
+		if item.kind == itemKindScoped && !inScope {
+			continue
+		}
+
+		func() {
+			ctx := pushGetter(ctx, item)
+			defer popGetter(ctx)
+
+			result.SetMapIndex(reflect.ValueOf(name).Convert(vt.Key()), reflect.ValueOf(item.getter(ctx)))
+		}()
+	}
+
+	return result
+}
+
+// getNamedItems returns the named builders visible from c which can be assigned to vt, and whose names start with prefix.
+func (c *factoryContext) getNamedItems(ctx context.Context, vt reflect.Type, prefix string) map[string]*contextCachedItem {
+	result := make(map[string]*contextCachedItem)
+	seen := make(map[string]bool)
+
+	for fc := c; fc != nil; fc = fc.parent {
+		fc.flushRegistrations()
+
+		fc.namedMapLock.RLock()
+		for k, v := range fc.namedMap {
+			if !seen[k] {
+				seen[k] = true
+				if strings.HasPrefix(k, prefix) && v._type.AssignableTo(vt) {
+					result[k] = v
+				}
+			}
+		}
+		fc.namedMapLock.RUnlock()
+	}
+
+	for k, v := range result {
+		if !v.enabled(ctx) {
+			delete(result, k)
+		}
+	}
+
+	return result
+}
+
+// getItemByName looks up the named builder in c first, then in its parents.
+func (c *factoryContext) getItemByName(ctx context.Context, name string) (*contextCachedItem, bool) {
+	for fc := c; fc != nil; fc = fc.parent {
+		fc.flushRegistrations()
+
+		fc.namedMapLock.RLock()
+		mb, ok := fc.namedMap[name]
+		fc.namedMapLock.RUnlock()
+
+		if ok && mb.enabled(ctx) {
+			return mb, true
+		}
+	}
+
+	return nil, false
+}
+
+// cachedItems returns the builders registered in c itself, typed ones first, each one only once.
+func (c *factoryContext) cachedItems() []*contextCachedItem {
+	c.flushRegistrations()
+
+	c.typedMapLock.RLock()
+	typedMap := structure.CloneMap(c.typedMap)
+	c.typedMapLock.RUnlock()
+
+	c.namedMapLock.RLock()
+	namedMap := structure.CloneMap(c.namedMap)
+	c.namedMapLock.RUnlock()
+
+	var types []reflect.Type
+	for k := range typedMap {
+		types = append(types, k)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		return types[i].String() < types[j].String()
+	})
+
+	var names []string
+	for k := range namedMap {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var result []*contextCachedItem
+	seen := make(map[*contextCachedItem]bool)
+	add := func(cci *contextCachedItem) {
+		if !seen[cci] {
+			seen[cci] = true
+			result = append(result, cci)
+		}
+	}
+
+	for _, t := range types {
+		add(typedMap[t])
+	}
+	for _, name := range names {
+		add(namedMap[name])
+	}
+
+	return result
+}
+
+// cloneTypedMap returns all typed builders visible from c, the builders of c override its parents'.
+func (c *factoryContext) cloneTypedMap(ctx context.Context) map[reflect.Type]*contextCachedItem {
+	result := make(map[reflect.Type]*contextCachedItem)
+
+	for fc := c; fc != nil; fc = fc.parent {
+		fc.flushRegistrations()
+
+		fc.typedMapLock.RLock()
+		for k, v := range fc.typedMap {
+			if _, ok := result[k]; !ok {
+				result[k] = v
+			}
+		}
+		fc.typedMapLock.RUnlock()
+	}
+
+	for k, v := range result {
+		if !v.enabled(ctx) {
+			delete(result, k)
+		}
+	}
+
+	return result
+}
+
+// setByName registers cci by name, after the pending registrations of c.
 func (c *factoryContext) setByName(name string, cci *contextCachedItem) {
+	c.flushRegistrations()
+	c.putByName(name, cci)
+}
+
+func (c *factoryContext) putByName(name string, cci *contextCachedItem) {
 	c.namedMapLock.Lock()
 	defer c.namedMapLock.Unlock()
 
@@ -302,17 +652,38 @@ func (c *factoryContext) setByName(name string, cci *contextCachedItem) {
 		panic(fmt.Errorf("Named builder allready exist: %s", name))
 	}
 
+	if _, ok := c.exprVars[name]; ok {
+		panic(fmt.Errorf("Named builder %s collides with the expr var", name))
+	}
+
+	if c.parent != nil {
+		if _, ok := c.parent.lookupExprVar(name); ok {
+			panic(fmt.Errorf("Named builder %s collides with the expr var", name))
+		}
+	}
+
 	c.namedMap[name] = cci
 }
 
-func (c *factoryContext) evalExpr(ctx context.Context, code string) (any, error) {
-	tree, _ := parser.Parse(code)
+// evalExpr evaluates code, its identifiers are looked up in locals first, then the registered expr vars and the named builders.
+func (c *factoryContext) evalExpr(ctx context.Context, locals map[string]any, code string) (any, error) {
+	compiled, err := c.compileExpr(code)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := c.getExprPolicy()
+	if err = policy.check(compiled, locals); err != nil {
+		return nil, err
+	}
 
-	exprCtx := &exprContext{ctx: ctx}
-	ast.Walk(&tree.Node, exprCtx)
+	run := policy.newRun(ctx)
+	defer run.cancel()
 
-	c.exprEnvLock.RLock()
-	defer c.exprEnvLock.RUnlock()
+	exprCtx := &exprContext{ctx: ctx, run: run, context: c, locals: locals, env: make(map[string]any, len(compiled.identifiers)+1)}
+	for _, identifier := range compiled.identifiers {
+		exprCtx.resolve(identifier)
+	}
 
-	return expr.Eval(code, c.exprEnvMap)
+	return run.run(compiled.program, exprCtx.env)
 }
@@ -3,11 +3,13 @@ module github.com/expgo/factory
 go 1.20
 
 require (
+	github.com/BurntSushi/toml v1.6.0
 	github.com/expgo/ag v0.0.0-20240507032547-4b7e9b00453b
 	github.com/expgo/structure v0.0.0-20240515010801-898cf0e94ad3
 	github.com/expgo/sync v0.0.0-20240603063239-cda8f6ce3df9
 	github.com/expr-lang/expr v1.16.9
 	github.com/stretchr/testify v1.9.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -16,5 +18,4 @@ require (
 	github.com/pmezard/go-difflib v1.0.1-0.20181226105442-5d4384ee4fb2 // indirect
 	github.com/sasha-s/go-deadlock v0.3.1 // indirect
 	golang.org/x/mod v0.17.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,563 @@
+package factory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GraphNode is a builder, a factory or a pool of a container. The ID of a builder overridden by a child container
+// ends with the depth of its container, such as *app.DB@1 for the one of the parent.
+type GraphNode struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+	Kind string `json:"kind"`
+}
+
+// GraphEdge means the From node depends on the To node.
+// Source is how the dependency is declared: wire, lazy (a Lazy wired field), value, new, init or param (the params of a factory),
+// or resolved if it's only seen when the objects were built.
+type GraphEdge struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Source string `json:"source"`
+	Field  string `json:"field,omitempty"`
+}
+
+type DependencyGraph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// JSON returns the graph encoded as indented JSON.
+func (g *DependencyGraph) JSON() ([]byte, error) {
+	return json.MarshalIndent(g, "", "  ")
+}
+
+// DOT returns the graph in the Graphviz DOT language.
+func (g *DependencyGraph) DOT() string {
+	sb := &strings.Builder{}
+	sb.WriteString("digraph factory {\n")
+	sb.WriteString("  node [shape=box];\n")
+
+	for _, n := range g.Nodes {
+		label := n.Type
+		if len(n.Name) > 0 {
+			label = n.Name + "\n" + n.Type
+		}
+		label += "\n<" + n.Kind + ">"
+		sb.WriteString(fmt.Sprintf("  %s [label=%s];\n", strconv.Quote(n.ID), strconv.Quote(label)))
+	}
+
+	for _, e := range g.Edges {
+		label := e.Source
+		if len(e.Field) > 0 {
+			label += ":" + e.Field
+		}
+		sb.WriteString(fmt.Sprintf("  %s -> %s [label=%s];\n", strconv.Quote(e.From), strconv.Quote(e.To), strconv.Quote(label)))
+	}
+
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// Graph returns the dependency graph of the default container, see Container.Graph.
+func Graph() *DependencyGraph {
+	return _context.graph()
+}
+
+// staticRef is a dependency declared by a 'wire', 'value' or 'new' tag, an init param or a factory param.
+type staticRef struct {
+	source string
+	field  string
+	vt     reflect.Type
+	tv     *TagWithValue // nil means got by type
+	locals []string      // the per-object identifiers visible to the exprs of a 'value' tag
+	err    error
+}
+
+// refTarget is a builder or a factory a staticRef refers to.
+type refTarget struct {
+	item    *contextCachedItem
+	factory *_factory
+}
+
+// typeRefs returns the dependencies of the *struct type vt built with option, nothing is built.
+func (c *factoryContext) typeRefs(vt reflect.Type, option *Option) (refs []*staticRef) {
+	if vt.Kind() != reflect.Ptr || vt.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+
+	if option == nil {
+		option = newDefaultOption
+	}
+
+	// the direct fields wired before, see exprLocals
+	var wired []string
+
+	err := walkTags(reflect.New(vt.Elem()).Interface(), []string{TagWire.Name(), TagValue.Name(), TagNew.Name()}, func(fieldValue reflect.Value, structField reflect.StructField, rootValues []reflect.Value, tags map[string]string) error {
+		if len(rootValues) == 1 {
+			defer func() { wired = append(wired, structField.Name) }()
+		}
+
+		if len(tags) > 1 {
+			refs = append(refs, &staticRef{source: TagWire.Name(), field: structField.Name, err: errors.New("Only one can exist at a time, either 'wire', 'value' or 'new'.")})
+			return nil
+		}
+
+		if newValue, ok := tags[TagNew.Name()]; ok {
+			refs = append(refs, &staticRef{source: TagNew.Name(), field: structField.Name, vt: structField.Type})
+
+			// the params of 'new' tag override the factory's params
+			newValue = strings.TrimSpace(newValue)
+			if f, loaded := c.getFactory(structField.Type); loaded && len(newValue) > 0 {
+				if newParams := strings.Split(newValue, ","); len(newParams) == len(f.params) {
+					refs = append(refs, c.factoryRefs(f, TagNew.Name()+":"+structField.Name, newParams)...)
+				}
+			}
+			return nil
+		}
+
+		ref := &staticRef{source: TagWire.Name(), field: structField.Name, vt: structField.Type}
+		if wireValue, ok := tags[TagWire.Name()]; ok {
+			ref.tv, ref.err = parseWireTag(wireValue, structField)
+			if ref.err == nil && ref.tv.Tag != WireValueSelf && isLazyType(ref.vt) {
+				ref.source = "lazy"
+				ref.vt = ref.vt.Out(0)
+			}
+		}
+		if wireValue, ok := tags[TagValue.Name()]; ok {
+			ref.source = TagValue.Name()
+			ref.tv = parseValueTag(wireValue)
+			ref.locals = append([]string{}, wired...)
+		}
+		refs = append(refs, ref)
+
+		return nil
+	})
+	if err != nil {
+		refs = append(refs, &staticRef{source: TagWire.Name(), err: err})
+	}
+
+	initMethodName := option.getInitMethodName(vt.Elem())
+	if initMethod, ok := vt.MethodByName(initMethodName); ok {
+		if initMethod.Type.NumOut() > 0 {
+			refs = append(refs, &staticRef{source: "init", field: initMethodName, err: fmt.Errorf("init method '%s' must not have return values", initMethodName)})
+		}
+		refs = append(refs, methodRefs("init", initMethod.Type, option.initParams, initMethodName)...)
+	}
+
+	return refs
+}
+
+// factoryRefs returns the dependencies of the factory f when it's called with params.
+func (c *factoryContext) factoryRefs(f *_factory, source string, params []string) []*staticRef {
+	if f.factoryType.Kind() == reflect.Func {
+		return methodRefs(source, f.factoryType, params, funcName(f.factory))
+	}
+
+	newMethod, ok := f.factoryType.MethodByName(f.methodName)
+	if !ok {
+		return []*staticRef{{source: source, field: f.methodName, err: fmt.Errorf("can't find new method from factory of type %s", f.returnType.String())}}
+	}
+
+	return methodRefs(source, newMethod.Type, params, newMethod.Name)
+}
+
+// methodRefs mirrors _getMethodParams, it returns the dependencies of the params of a method.
+func methodRefs(source string, methodType reflect.Type, methodParams []string, methodName string) (refs []*staticRef) {
+	baseIndex := methodType.NumIn() - len(methodParams)
+
+	if len(methodParams) == 0 {
+		for i := 1; i < methodType.NumIn(); i++ {
+			ref := &staticRef{source: source, field: fmt.Sprintf("%s#%d", methodName, i), vt: methodType.In(i)}
+			if !isWirableType(ref.vt) {
+				ref.err = fmt.Errorf("method %s's %d argument must be a struct point or an interface", methodName, i)
+			}
+			refs = append(refs, ref)
+		}
+	} else if baseIndex == 0 || baseIndex == 1 {
+		for i := 0; i < methodType.NumIn()-baseIndex; i++ {
+			ref := &staticRef{source: source, field: fmt.Sprintf("%s#%d", methodName, i+baseIndex), vt: methodType.In(i + baseIndex)}
+
+			tagValue, err := ParseTagValue(methodParams[i], nil)
+			if err != nil {
+				ref.err = fmt.Errorf("method %s's %d argument tag is err: %v", methodName, i+baseIndex, err)
+			} else {
+				ref.tv = tagValue
+			}
+			refs = append(refs, ref)
+		}
+	} else {
+		refs = append(refs, &staticRef{source: source, field: methodName, err: errors.New("init params count must equals with method params count")})
+	}
+
+	return refs
+}
+
+// funcName returns the name of the func fn without its package path, such as factory.newStore,
+// or its type if it can't be found.
+func funcName(fn any) string {
+	if f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()); f != nil {
+		name := f.Name()
+		return name[strings.LastIndex(name, "/")+1:]
+	}
+	return reflect.TypeOf(fn).String()
+}
+
+func isWirableType(t reflect.Type) bool {
+	return (t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct) || t.Kind() == reflect.Interface
+}
+
+// resolveRef finds the targets of ref like getValueByWireTag does, but nothing is built.
+func (c *factoryContext) resolveRef(ctx context.Context, ref *staticRef) ([]refTarget, error) {
+	if ref.err != nil {
+		return nil, ref.err
+	}
+
+	if ref.source == TagNew.Name() && ref.tv == nil {
+		f, loaded := c.getFactory(ref.vt)
+		if !loaded {
+			return nil, newResolveError(ErrNotFound, nil, "can't get factory type of %s", ref.vt.String())
+		}
+		return []refTarget{{factory: f}}, nil
+	}
+
+	byType := func() ([]refTarget, error) {
+		cci, err := c.lookupItemByType(ctx, ref.vt)
+		if err != nil {
+			return nil, err
+		}
+		return []refTarget{{item: cci}}, nil
+	}
+
+	byName := func(name string) (*contextCachedItem, error) {
+		if cci, ok := c.getItemByName(ctx, name); ok {
+			if ref.vt.ConvertibleTo(cci._type) {
+				return cci, nil
+			}
+		}
+		return nil, newResolveError(ErrNotFound, nil, "Named builder %s not found.", name)
+	}
+
+	if ref.tv == nil {
+		return byType()
+	}
+
+	switch ref.tv.Tag {
+	case WireValueSelf, WireValueAuto, WireValueType, WireValueName:
+		if !isWirableType(ref.vt) {
+			return nil, errors.New("‘self’, ’auto’, ’type’ and ’name’ tag only used on *struct or interface")
+		}
+
+		switch ref.tv.Tag {
+		case WireValueSelf:
+			return nil, nil
+		case WireValueAuto:
+			if len(ref.tv.Value) > 0 {
+				if cci, err := byName(ref.tv.Value); err == nil {
+					return []refTarget{{item: cci}}, nil
+				}
+			}
+			return byType()
+		case WireValueType:
+			return byType()
+		case WireValueName:
+			if len(ref.tv.Value) > 0 {
+				cci, err := byName(ref.tv.Value)
+				if err != nil {
+					return nil, err
+				}
+				return []refTarget{{item: cci}}, nil
+			}
+		}
+	case WireValueAll:
+		if ref.vt.Kind() != reflect.Slice || !isWirableType(ref.vt.Elem()) {
+			return nil, errors.New("'all' tag only used on a slice of *struct or interface")
+		}
+
+		var targets []refTarget
+		for _, cci := range c.getAllItems(ctx, ref.vt.Elem()) {
+			targets = append(targets, refTarget{item: cci})
+		}
+		return targets, nil
+	case WireValueMap:
+		if ref.vt.Kind() != reflect.Map || ref.vt.Key().Kind() != reflect.String || !isWirableType(ref.vt.Elem()) {
+			return nil, errors.New("'map' tag only used on a map of string to *struct or interface")
+		}
+
+		items := c.getNamedItems(ctx, ref.vt.Elem(), ref.tv.Value)
+		var names []string
+		for name := range items {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var targets []refTarget
+		for _, name := range names {
+			targets = append(targets, refTarget{item: items[name]})
+		}
+		return targets, nil
+	case WireValueValue:
+		if ((ref.vt.Kind() == reflect.Ptr && ref.vt.Elem().Kind() == reflect.Struct) || ref.vt.Kind() == reflect.Struct) && !isConvertible(ref.vt) {
+			return nil, errors.New("'value' tag can't used on *struct or struct without a converter")
+		}
+
+		if len(ref.tv.Value) > 0 {
+			segments, err := parseTemplate(strings.TrimSpace(ref.tv.Value))
+			if err != nil {
+				return nil, fmt.Errorf("tag value %s parse err: %w", ref.tv, err)
+			}
+
+			locals := map[string]any{SelfIdentifier: nil}
+			for _, local := range ref.locals {
+				locals[local] = nil
+			}
+
+			identifiers, err := c.templateIdentifiers(ctx, locals, segments)
+			if err != nil {
+				return nil, fmt.Errorf("tag value %s expr compile err: %w", ref.tv, err)
+			}
+
+			if err = c.checkTemplatePolicy(ctx, locals, segments); err != nil {
+				return nil, fmt.Errorf("tag value %s expr err: %w", ref.tv, err)
+			}
+
+			var targets []refTarget
+			for _, identifier := range identifiers {
+				if _, ok := locals[identifier]; ok {
+					continue
+				}
+				if _, ok := c.lookupExprVar(identifier); ok {
+					continue
+				}
+
+				cci, ok := c.getItemByName(ctx, identifier)
+				if !ok {
+					return nil, newResolveError(ErrNotFound, nil, "Named builder %s not found.", identifier)
+				}
+				targets = append(targets, refTarget{item: cci})
+			}
+			return targets, nil
+		}
+	}
+
+	return nil, fmt.Errorf("tag value not supported: %+v", ref.tv)
+}
+
+// graph returns the builders, factories and pools visible from c, and the dependencies between them.
+// Static edges come from the tags and params of the registered types, resolved edges from the objects already built.
+func (c *factoryContext) graph() *DependencyGraph {
+	g, itemIDs := c.staticGraph(nil)
+
+	edges := make(map[[2]string]bool)
+	for _, edge := range g.Edges {
+		edges[[2]string{edge.From, edge.To}] = true
+	}
+
+	// the edges seen while building, which aren't declared statically
+	for fc := c; fc != nil; fc = fc.parent {
+		fc.lifecycle.lock.Lock()
+		var resolved []GraphEdge
+		for from, deps := range fc.lifecycle.dependencies {
+			for to := range deps {
+				fromID, ok1 := itemIDs[from]
+				toID, ok2 := itemIDs[to]
+				if ok1 && ok2 && !edges[[2]string{fromID, toID}] {
+					resolved = append(resolved, GraphEdge{From: fromID, To: toID, Source: "resolved"})
+				}
+			}
+		}
+		fc.lifecycle.lock.Unlock()
+
+		sort.Slice(resolved, func(i, j int) bool {
+			if resolved[i].From != resolved[j].From {
+				return resolved[i].From < resolved[j].From
+			}
+			return resolved[i].To < resolved[j].To
+		})
+		for _, edge := range resolved {
+			edges[[2]string{edge.From, edge.To}] = true
+			g.Edges = append(g.Edges, edge)
+		}
+	}
+
+	return g
+}
+
+// staticGraph returns the graph declared by the registrations visible from c, and the node IDs of the builders.
+// Nothing is built, the refs can't be resolved are passed to onError if it isn't nil.
+func (c *factoryContext) staticGraph(onError func(node string, ref *staticRef, err error)) (*DependencyGraph, map[*contextCachedItem]string) {
+	g := &DependencyGraph{}
+
+	itemIDs := make(map[*contextCachedItem]string)
+	nodeIDs := make(map[string]bool)
+	addNode := func(node GraphNode) {
+		if !nodeIDs[node.ID] {
+			nodeIDs[node.ID] = true
+			g.Nodes = append(g.Nodes, node)
+		}
+	}
+
+	type owned struct {
+		fc  *factoryContext
+		cci *contextCachedItem
+	}
+	var items []owned
+
+	type ownedFactory struct {
+		fc *factoryContext
+		f  *_factory
+	}
+	var factories []ownedFactory
+
+	type ownedPool struct {
+		fc     *factoryContext
+		vt     reflect.Type
+		option *Option
+	}
+	var pools []ownedPool
+
+	// the child's builders first, the ones overridden in the child are still listed
+	for fc, depth := c, 0; fc != nil; fc, depth = fc.parent, depth+1 {
+		fc.flushRegistrations()
+
+		names := make(map[*contextCachedItem]string)
+		fc.namedMapLock.RLock()
+		for name, cci := range fc.namedMap {
+			names[cci] = name
+		}
+		fc.namedMapLock.RUnlock()
+
+		for _, cci := range fc.cachedItems() {
+			if _, ok := itemIDs[cci]; ok {
+				continue
+			}
+
+			enabled := false
+			if err := try(func() { enabled = cci.enabled(context.Background()) }); err != nil {
+				if onError != nil {
+					onError(cci._type.String(), nil, err)
+				}
+				continue
+			} else if !enabled {
+				continue
+			}
+
+			node := GraphNode{ID: cci._type.String(), Type: cci._type.String(), Name: names[cci], Kind: string(cci.kind)}
+			if len(node.Name) > 0 {
+				fc.typedMapLock.RLock()
+				typed := fc.typedMap[cci._type] == cci
+				fc.typedMapLock.RUnlock()
+				if !typed {
+					node.ID = node.Name
+				}
+			}
+			if nodeIDs[node.ID] {
+				// overridden by a child container
+				node.ID = fmt.Sprintf("%s@%d", node.ID, depth)
+			}
+
+			itemIDs[cci] = node.ID
+			addNode(node)
+			items = append(items, owned{fc: fc, cci: cci})
+		}
+
+		fc.factoriesLock.RLock()
+		var fs []*_factory
+		for _, f := range fc.factories {
+			fs = append(fs, f)
+		}
+		fc.factoriesLock.RUnlock()
+		sort.Slice(fs, func(i, j int) bool {
+			return fs[i].returnType.String() < fs[j].returnType.String()
+		})
+		for _, f := range fs {
+			if nodeIDs[factoryNodeID(f)] {
+				continue
+			}
+			addNode(GraphNode{ID: factoryNodeID(f), Type: f.returnType.String(), Kind: string(itemKindFactory)})
+			factories = append(factories, ownedFactory{fc: fc, f: f})
+		}
+
+		fc.pools.lock.RLock()
+		for vt, option := range fc.pools.options {
+			pools = append(pools, ownedPool{fc: fc, vt: vt, option: option})
+		}
+		fc.pools.lock.RUnlock()
+	}
+
+	sort.SliceStable(pools, func(i, j int) bool {
+		return pools[i].vt.String() < pools[j].vt.String()
+	})
+	for _, p := range pools {
+		addNode(GraphNode{ID: poolNodeID(p.vt), Type: reflect.PointerTo(p.vt).String(), Kind: string(itemKindPool)})
+	}
+
+	addRefs := func(fc *factoryContext, from string, refs []*staticRef) {
+		for _, ref := range refs {
+			targets, err := fc.resolveRef(context.Background(), ref)
+			if err != nil && ref.tv != nil && ref.tv.Optional && isNotFound(err) {
+				continue
+			}
+			if err != nil {
+				if onError != nil {
+					onError(from, ref, err)
+				}
+				continue
+			}
+
+			for _, target := range targets {
+				to := ""
+				if target.factory != nil {
+					to = factoryNodeID(target.factory)
+				} else if id, ok := itemIDs[target.item]; ok {
+					to = id
+				}
+
+				if len(to) > 0 {
+					g.Edges = append(g.Edges, GraphEdge{From: from, To: to, Source: ref.source, Field: ref.field})
+				}
+			}
+		}
+	}
+
+	for _, item := range items {
+		if item.cci.checkValid != nil && onError != nil {
+			if err := try(item.cci.checkValid); err != nil {
+				onError(itemIDs[item.cci], nil, err)
+			}
+		}
+		if item.cci.option != nil {
+			addRefs(item.fc, itemIDs[item.cci], item.fc.typeRefs(item.cci._type, item.cci.option))
+		}
+	}
+	for _, f := range factories {
+		if onError != nil {
+			if err := try(f.f.CheckValid); err != nil {
+				onError(factoryNodeID(f.f), nil, err)
+			}
+		}
+		addRefs(f.fc, factoryNodeID(f.f), f.fc.factoryRefs(f.f, "param", f.f.params))
+	}
+	for _, p := range pools {
+		addRefs(p.fc, poolNodeID(p.vt), p.fc.typeRefs(reflect.PointerTo(p.vt), p.option))
+	}
+
+	return g, itemIDs
+}
+
+func factoryNodeID(f *_factory) string {
+	return "factory:" + f.returnType.String()
+}
+
+func poolNodeID(vt reflect.Type) string {
+	return "pool:" + reflect.PointerTo(vt).String()
+}
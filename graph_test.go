@@ -0,0 +1,120 @@
+package factory
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type graphStore interface {
+	Load() string
+}
+
+type graphDB struct{}
+
+func (d *graphDB) Load() string {
+	return "db"
+}
+
+type graphCache struct {
+	Store *graphDB `wire:"name:db"`
+}
+
+type graphService struct {
+	Cache  *graphCache `wire:"auto"`
+	Store  *graphDB    `wire:"name:db"`
+	Prefix string      `value:"${env.HOME}"`
+	Helper graphStore  `new:""`
+}
+
+func (s *graphService) Init(cache *graphCache) {}
+
+type graphPooled struct {
+	Cache *graphCache `wire:"auto"`
+}
+
+func newGraphStore() graphStore {
+	return &graphDB{}
+}
+
+func TestGraph(t *testing.T) {
+	c := NewContainer()
+	c.NamedSingleton("db", new(graphDB))
+	c.Singleton(new(graphCache))
+	c.Singleton(new(graphService))
+	c.Factory(new(graphStore), newGraphStore)
+	c.SetPoolInit(new(graphPooled), nil)
+
+	g := c.Graph()
+
+	kinds := map[string]string{}
+	for _, n := range g.Nodes {
+		kinds[n.ID] = n.Kind
+	}
+	assert.Equal(t, "singleton", kinds["db"])
+	assert.Equal(t, "singleton", kinds["*factory.graphCache"])
+	assert.Equal(t, "singleton", kinds["env"])
+	assert.Equal(t, "factory", kinds["factory:factory.graphStore"])
+	assert.Equal(t, "pool", kinds["pool:*factory.graphPooled"])
+
+	assert.Contains(t, g.Edges, GraphEdge{From: "*factory.graphCache", To: "db", Source: "wire", Field: "Store"})
+	assert.Contains(t, g.Edges, GraphEdge{From: "*factory.graphService", To: "*factory.graphCache", Source: "wire", Field: "Cache"})
+	assert.Contains(t, g.Edges, GraphEdge{From: "*factory.graphService", To: "db", Source: "wire", Field: "Store"})
+	assert.Contains(t, g.Edges, GraphEdge{From: "*factory.graphService", To: "env", Source: "value", Field: "Prefix"})
+	assert.Contains(t, g.Edges, GraphEdge{From: "*factory.graphService", To: "factory:factory.graphStore", Source: "new", Field: "Helper"})
+	assert.Contains(t, g.Edges, GraphEdge{From: "*factory.graphService", To: "*factory.graphCache", Source: "init", Field: "Init#1"})
+	assert.Contains(t, g.Edges, GraphEdge{From: "pool:*factory.graphPooled", To: "*factory.graphCache", Source: "wire", Field: "Cache"})
+
+	dot := g.DOT()
+	assert.Contains(t, dot, "digraph factory {")
+	assert.Contains(t, dot, `"*factory.graphCache" -> "db" [label="wire:Store"];`)
+
+	data, err := g.JSON()
+	assert.Nil(t, err)
+
+	var decoded DependencyGraph
+	assert.Nil(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, g, &decoded)
+}
+
+type graphHolder struct {
+	Helper graphStore `new:""`
+}
+
+func newGraphCachedStore(cache *graphCache) graphStore {
+	return cache.Store
+}
+
+func TestGraphResolved(t *testing.T) {
+	c := NewContainer()
+	c.NamedSingleton("db", new(graphDB))
+	c.Singleton(new(graphCache))
+	c.Singleton(new(graphHolder))
+	c.Factory(new(graphStore), newGraphCachedStore).Params("type")
+
+	g := c.Graph()
+	assert.Contains(t, g.Edges, GraphEdge{From: "*factory.graphHolder", To: "factory:factory.graphStore", Source: "new", Field: "Helper"})
+	assert.Contains(t, g.Edges, GraphEdge{From: "factory:factory.graphStore", To: "*factory.graphCache", Source: "param", Field: "factory.newGraphCachedStore#0"})
+	assert.NotContains(t, g.Edges, GraphEdge{From: "*factory.graphHolder", To: "*factory.graphCache", Source: "resolved"})
+
+	var holder *graphHolder
+	c.Find(&holder)
+
+	// the factory is called while building the holder
+	g = c.Graph()
+	assert.Contains(t, g.Edges, GraphEdge{From: "*factory.graphHolder", To: "*factory.graphCache", Source: "resolved"})
+}
+
+func TestGraphOverridden(t *testing.T) {
+	parent := NewContainer()
+	parent.Singleton(new(graphDB))
+	child := parent.Child()
+	child.Singleton(new(graphDB))
+
+	var ids []string
+	for _, node := range child.Graph().Nodes {
+		ids = append(ids, node.ID)
+	}
+	assert.Contains(t, ids, "*factory.graphDB")
+	assert.Contains(t, ids, "*factory.graphDB@1")
+}
@@ -2,50 +2,58 @@ package factory
 
 import (
 	"context"
-	"fmt"
 	"github.com/expgo/sync"
 	"reflect"
 	"strings"
+	"time"
 )
 
 type iInterface struct {
-	once     sync.Once
+	once     *buildOnce
 	obj      any
 	initFunc func() any
+	option   Option // only the destroy settings are used
 
-	_name string
-	lock  sync.Mutex
+	_name   string
+	lock    sync.Mutex
+	context *factoryContext
 
 	cci *contextCachedItem
 }
 
-func _interfaceWithType(vt reflect.Type) *iInterface {
+func _interfaceWithType(c *factoryContext, vt reflect.Type) *iInterface {
 	result := &iInterface{
-		once: sync.NewOnce(),
-		lock: sync.NewMutex(),
+		once:    newBuildOnce(),
+		lock:    sync.NewMutex(),
+		context: c,
+		option: Option{
+			lock: sync.NewMutex(),
+		},
 	}
 
-	result.cci = &contextCachedItem{}
+	result.cci = &contextCachedItem{context: c}
 
 	result.cci._type = vt
+	result.cci.kind = itemKindInterface
 
 	result.cci.getter = func(ctx context.Context) any {
 		return result.getWithContext(ctx)
 	}
+	result.cci.checkValid = result.checkValid
 
 	return result
 }
 
 func Interface[T any]() *iInterface {
-	return _interfaceWithType(reflect.TypeOf((*T)(nil)).Elem()).setType()
+	return _interfaceWithType(_context, reflect.TypeOf((*T)(nil)).Elem()).setType()
 }
 
 func NamedInterface[T any](name string) *iInterface {
-	return _interfaceWithType(reflect.TypeOf((*T)(nil)).Elem()).Name(name)
+	return _interfaceWithType(_context, reflect.TypeOf((*T)(nil)).Elem()).Name(name)
 }
 
 func (s *iInterface) setType() *iInterface {
-	_context.setByType(s.cci._type, s.cci)
+	s.context.setByType(s.cci._type, s.cci)
 	return s
 }
 
@@ -59,7 +67,7 @@ func (s *iInterface) Name(name string) *iInterface {
 	}
 
 	if len(s._name) == 0 {
-		_context.setByName(name, s.cci)
+		s.context.setByName(name, s.cci)
 		s._name = name
 	} else {
 		panic("name already set")
@@ -76,26 +84,49 @@ func (s *iInterface) SetInitFunc(initFunc func() any) *iInterface {
 	return s
 }
 
+// Order sets the position of the interface in the slices injected by 'wire:"all"', the lower ones come first.
+func (s *iInterface) Order(order int) *iInterface {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.cci.order = order
+	return s
+}
+
+// DestroyTimeout sets the time given to destroy the object, 0 uses Opts.DestroyTimeout.
+func (s *iInterface) DestroyTimeout(destroyTimeout time.Duration) *iInterface {
+	s.option.DestroyTimeout(destroyTimeout)
+	return s
+}
+
+func (s *iInterface) checkValid() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.initFunc == nil {
+		panic("initFunc must be set")
+	}
+}
+
 func (s *iInterface) getWithContext(ctx context.Context) any {
 	timeout := getContextTimeout(ctx)
-	err := s.once.DoTimeout(timeout, func() error {
+	s.once.do(timeout, func() {
 		if s.initFunc != nil {
 			s.obj = s.initFunc()
 		} else {
 			panic("initFunc must be set")
 		}
-		return nil
-	})
 
-	if err != nil {
-		panic(fmt.Errorf("init interface %s, timeout: %s err: %+v", s.cci._type.String(), timeout, err))
-	}
+		s.context.addCreated(s.cci, s.obj, &s.option)
+	}, func(err error) error {
+		return newResolveError(ErrTimeout, err, "init interface %s, timeout: %s err: %+v", s.cci._type.String(), timeout, err)
+	})
 
 	return s.obj
 }
 
 func IGetter[T any](s *iInterface) func() T {
 	return func() T {
-		return s.getWithContext(getTimeoutContext(Opts.Timeout)).(T)
+		return s.getWithContext(s.context.timeoutContext(Opts.Timeout)).(T)
 	}
 }
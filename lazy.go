@@ -0,0 +1,64 @@
+package factory
+
+import (
+	"context"
+	"github.com/expgo/sync"
+	"reflect"
+)
+
+// Lazy is a handle of a dependency which is got through the container on its first Get, and cached after that.
+// T must be a *struct or an interface, such as:
+//
+//	type Service struct {
+//		Metrics factory.Lazy[*Metrics] `wire:"auto"`
+//	}
+//
+// As the dependency isn't got while building, Lazy breaks the circular references between builders.
+// Fields of type func() T are wired in the same way. A Lazy wired into a scoped object is bound to the scope
+// it's wired in, its first Get fails once the scope ends, see WithScope.
+type Lazy[T any] func() T
+
+// Get returns the dependency, it's safe to be called from several goroutines.
+func (l Lazy[T]) Get() T {
+	return l()
+}
+
+// isLazyType reports whether t is a Lazy or a func() T, where T is a *struct or an interface.
+func isLazyType(t reflect.Type) bool {
+	return t.Kind() == reflect.Func && t.NumIn() == 0 && t.NumOut() == 1 && !t.IsVariadic() && isWirableType(t.Out(0))
+}
+
+// lazyFunc returns a func of type t, which gets the object by tagValue on its first call.
+// The object is got with a new context, only the scope of ctx is kept, so it fails once the scope ends.
+func lazyFunc(ctx context.Context, self any, tagValue *TagWithValue, t reflect.Type) reflect.Value {
+	c := getContextContainer(ctx)
+	scope := ctx.Value(ScopeKey)
+
+	lock := sync.NewMutex()
+	var result []reflect.Value
+
+	return reflect.MakeFunc(t, func([]reflect.Value) []reflect.Value {
+		lock.Lock()
+		defer lock.Unlock()
+
+		if result == nil {
+			lazyCtx := initTypeCtx(c.timeoutContext(Opts.Timeout))
+			if scope != nil {
+				lazyCtx = context.WithValue(lazyCtx, ScopeKey, scope)
+			}
+
+			value := reflect.New(t.Out(0)).Elem()
+			v, found, err := getOptionalValueByWireTag(lazyCtx, self, tagValue, t.Out(0))
+			if err != nil {
+				panic(err)
+			}
+			if found {
+				value.Set(reflect.ValueOf(v))
+			}
+
+			result = []reflect.Value{value}
+		}
+
+		return result
+	})
+}
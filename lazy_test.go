@@ -0,0 +1,94 @@
+package factory
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+type lazyParent struct {
+	Child *lazyChild `wire:"auto"`
+}
+
+type lazyChild struct {
+	Parent  Lazy[*lazyParent] `wire:"auto"`
+	Storage func() tryStorage `wire:"type"`
+	Missing Lazy[*tryMissing] `wire:"auto,optional"`
+}
+
+type lazyExpensive struct{}
+
+type lazyHolder struct {
+	Expensive Lazy[*lazyExpensive] `wire:"auto"`
+}
+
+func TestLazy(t *testing.T) {
+	c := NewContainer()
+	c.Singleton(new(lazyParent))
+	c.Singleton(new(lazyChild))
+	c.Singleton(new(tryDisk))
+
+	assert.Nil(t, c.Validate())
+
+	var parent *lazyParent
+	c.Find(&parent)
+	assert.Same(t, parent, parent.Child.Parent.Get())
+	assert.IsType(t, &tryDisk{}, parent.Child.Storage())
+	assert.Nil(t, parent.Child.Missing.Get())
+}
+
+func TestLazyFirstGet(t *testing.T) {
+	inits := 0
+
+	c := NewContainer()
+	c.Singleton(new(lazyExpensive)).SetInitFunc(func() any {
+		inits++
+		return &lazyExpensive{}
+	})
+
+	var holder *lazyHolder
+	c.New(&holder)
+	assert.Equal(t, 0, inits)
+
+	wg := sync.WaitGroup{}
+	results := make([]*lazyExpensive, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = holder.Expensive.Get()
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, inits)
+	for _, result := range results {
+		assert.Same(t, results[0], result)
+	}
+}
+
+type lazyScoped struct {
+	Expensive Lazy[*lazyExpensive] `wire:"auto"`
+}
+
+func TestLazyScoped(t *testing.T) {
+	c := NewContainer()
+	c.Scoped(new(lazyExpensive))
+	c.Scoped(new(lazyScoped))
+
+	ctx, cancel := WithScope(context.Background())
+	defer cancel()
+
+	var scoped *lazyScoped
+	c.FindContext(ctx, &scoped)
+	var expensive *lazyExpensive
+	c.FindContext(ctx, &expensive)
+	assert.Same(t, expensive, scoped.Expensive.Get())
+
+	// bound to the scope it's wired in
+	ctx, cancel = WithScope(context.Background())
+	c.FindContext(ctx, &scoped)
+	cancel()
+	assert.PanicsWithError(t, "getting *factory.lazyExpensive, scope already closed", func() { scoped.Expensive.Get() })
+}
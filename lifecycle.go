@@ -0,0 +1,282 @@
+package factory
+
+import (
+	"context"
+	"fmt"
+	"github.com/expgo/sync"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// DefaultDestroyMethodName is the method called when an object is destroyed,
+// if the object doesn't implement io.Closer and no destroy method name is set in its Option.
+const DefaultDestroyMethodName = "Destroy"
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// lifecycle records the objects created by a container and the dependencies between their builders,
+// so they can be destroyed in the reverse order.
+type lifecycle struct {
+	created      []*createdObject // in creation order
+	dependencies map[*contextCachedItem]map[*contextCachedItem]struct{}
+	lock         sync.Mutex
+}
+
+type createdObject struct {
+	cci               *contextCachedItem
+	obj               any
+	destroyMethodName string
+	destroyTimeout    time.Duration // 0 uses Opts.DestroyTimeout
+}
+
+// newCreatedObject returns obj built by cci, it's destroyed as option says, option may be nil.
+func newCreatedObject(cci *contextCachedItem, obj any, option *Option) *createdObject {
+	result := &createdObject{cci: cci, obj: obj}
+	if option != nil {
+		result.destroyMethodName = option.destroyMethodName
+		result.destroyTimeout = option.destroyTimeout
+	}
+	return result
+}
+
+func newLifecycle() *lifecycle {
+	return &lifecycle{
+		dependencies: make(map[*contextCachedItem]map[*contextCachedItem]struct{}),
+		lock:         sync.NewMutex(),
+	}
+}
+
+func (c *factoryContext) addCreated(cci *contextCachedItem, obj any, option *Option) {
+	c.lifecycle.lock.Lock()
+	defer c.lifecycle.lock.Unlock()
+
+	c.lifecycle.created = append(c.lifecycle.created, newCreatedObject(cci, obj, option))
+}
+
+// addDependency records that from depends on to.
+func (c *factoryContext) addDependency(from, to *contextCachedItem) {
+	c.lifecycle.lock.Lock()
+	defer c.lifecycle.lock.Unlock()
+
+	deps, ok := c.lifecycle.dependencies[from]
+	if !ok {
+		deps = make(map[*contextCachedItem]struct{})
+		c.lifecycle.dependencies[from] = deps
+	}
+	deps[to] = struct{}{}
+}
+
+// Start builds all singletons of the default container, see Container.Start.
+func Start(ctx context.Context) error {
+	return _context.start(ctx, true)
+}
+
+// StartCollect is like Start, but it builds all singletons even if some of them fail,
+// and returns all errors together.
+func StartCollect(ctx context.Context) error {
+	return _context.start(ctx, false)
+}
+
+func (c *factoryContext) start(ctx context.Context, failFast bool) error {
+	ctx = withContainer(context.WithValue(ctx, TimeoutKey, Opts.Timeout), c)
+
+	var errs []string
+	var items []*contextCachedItem
+
+	// evaluate the conditions of all registrations before building anything
+	for _, cci := range c.cachedItems() {
+		enabled := false
+		if err := try(func() { enabled = cci.enabled(ctx) }); err != nil {
+			err = fmt.Errorf("condition of %s err: %w", cci._type.String(), err)
+			if failFast {
+				return err
+			}
+			errs = appendErrors(errs, err)
+		} else if enabled {
+			items = append(items, cci)
+		}
+	}
+
+	for _, cci := range items {
+		if (cci.kind != itemKindSingleton && cci.kind != itemKindInterface) || cci.lazy {
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := startItem(ctx, cci); err != nil {
+			if failFast {
+				return err
+			}
+			errs = appendErrors(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return &Error{Errors: errs}
+	}
+
+	return nil
+}
+
+func startItem(ctx context.Context, cci *contextCachedItem) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("start %s err: %w", cci._type.String(), recoverError(r))
+		}
+	}()
+
+	ctx = pushGetter(ctx, cci)
+	defer popGetter(ctx)
+
+	cci.getter(ctx)
+	return nil
+}
+
+// Shutdown destroys the objects created by the default container, see Container.Shutdown.
+func Shutdown(ctx context.Context) error {
+	return _context.shutdown(ctx)
+}
+
+func (c *factoryContext) shutdown(ctx context.Context) error {
+	c.lifecycle.lock.Lock()
+	created := c.lifecycle.created
+	dependencies := c.lifecycle.dependencies
+	c.lifecycle.created = nil
+	c.lifecycle.dependencies = make(map[*contextCachedItem]map[*contextCachedItem]struct{})
+	c.lifecycle.lock.Unlock()
+
+	var errs []string
+	for _, co := range destroyOrder(created, dependencies) {
+		if err := destroyTimeout(ctx, co); err != nil {
+			errs = appendErrors(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return &Error{Errors: errs}
+	}
+
+	return nil
+}
+
+// destroyOrder sorts created topologically, the latest created object first, and every object after the ones depending on it.
+// A circular dependency is broken at the object it's reached from, which is destroyed after the others of the cycle.
+func destroyOrder(created []*createdObject, dependencies map[*contextCachedItem]map[*contextCachedItem]struct{}) []*createdObject {
+	dependents := make(map[*contextCachedItem][]int)
+	for i, co := range created {
+		for to := range dependencies[co.cci] {
+			dependents[to] = append(dependents[to], i)
+		}
+	}
+
+	result := make([]*createdObject, 0, len(created))
+	visited := make([]bool, len(created))
+
+	var visit func(index int)
+	visit = func(index int) {
+		if visited[index] {
+			return
+		}
+		visited[index] = true
+
+		// in creation order, so the latest one is visited first
+		indexes := dependents[created[index].cci]
+		for i := len(indexes) - 1; i >= 0; i-- {
+			visit(indexes[i])
+		}
+
+		result = append(result, created[index])
+	}
+
+	for i := len(created) - 1; i >= 0; i-- {
+		visit(i)
+	}
+
+	return result
+}
+
+func destroyTimeout(ctx context.Context, co *createdObject) (err error) {
+	timeout := co.destroyTimeout
+	if timeout <= 0 {
+		timeout = Opts.DestroyTimeout
+	}
+
+	if err = ctx.Err(); err == nil {
+		done := make(chan error, 1)
+		go func() {
+			defer func() {
+				if r := recover(); r != nil {
+					done <- fmt.Errorf("%v", r)
+				}
+			}()
+
+			done <- destroyObject(co.obj, co.destroyMethodName)
+		}()
+
+		var timeoutCh <-chan time.Time
+		if timeout > 0 {
+			timer := time.NewTimer(timeout)
+			defer timer.Stop()
+			timeoutCh = timer.C
+		}
+
+		select {
+		case err = <-done:
+		case <-timeoutCh:
+			err = fmt.Errorf("timeout: %s", timeout)
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("destroy %s err: %v", co.cci._type.String(), err)
+	}
+
+	return nil
+}
+
+func destroyObject(obj any, destroyMethodName string) error {
+	if obj == nil {
+		return nil
+	}
+
+	vt := reflect.TypeOf(obj)
+
+	if len(destroyMethodName) == 0 {
+		if closer, ok := obj.(io.Closer); ok {
+			return closer.Close()
+		}
+
+		if _, ok := vt.MethodByName(DefaultDestroyMethodName); !ok {
+			return nil
+		}
+
+		destroyMethodName = DefaultDestroyMethodName
+	}
+
+	// 确保方法的第一个字母为大写
+	destroyMethodName = strings.ToTitle(destroyMethodName[:1]) + destroyMethodName[1:]
+
+	method, ok := vt.MethodByName(destroyMethodName)
+	if !ok {
+		return fmt.Errorf("destroy method '%s' not found", destroyMethodName)
+	}
+
+	mt := method.Type
+	if mt.NumIn() != 1 || mt.NumOut() > 1 || (mt.NumOut() == 1 && mt.Out(0) != errorType) {
+		return fmt.Errorf("destroy method '%s' must have no params, and return nothing or an error", destroyMethodName)
+	}
+
+	results := method.Func.Call([]reflect.Value{reflect.ValueOf(obj)})
+	if len(results) == 1 && !results[0].IsNil() {
+		return results[0].Interface().(error)
+	}
+
+	return nil
+}
@@ -0,0 +1,205 @@
+package factory
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var destroyed []string
+
+type lifecycleDB struct{}
+
+func (d *lifecycleDB) Close() error {
+	destroyed = append(destroyed, "db")
+	return nil
+}
+
+type lifecycleRepo struct {
+	DB *lifecycleDB `wire:"auto"`
+}
+
+func (r *lifecycleRepo) Destroy() {
+	destroyed = append(destroyed, "repo")
+}
+
+type lifecycleService struct {
+	Repo *lifecycleRepo `wire:"auto"`
+}
+
+func (s *lifecycleService) Stop() error {
+	destroyed = append(destroyed, "service")
+	return errors.New("stop failed")
+}
+
+type lifecycleSlow struct{}
+
+func (s *lifecycleSlow) Destroy() {
+	time.Sleep(time.Second)
+}
+
+func TestShutdown(t *testing.T) {
+	destroyed = nil
+
+	c := NewContainer()
+	c.Singleton(new(lifecycleDB))
+	c.Singleton(new(lifecycleRepo))
+	c.Singleton(new(lifecycleService)).DestroyMethodName("stop")
+	c.Singleton(new(lifecycleSlow))
+
+	// create the db first, the service is still destroyed before it
+	var db *lifecycleDB
+	c.Find(&db)
+	var slow *lifecycleSlow
+	c.Find(&slow)
+	var service *lifecycleService
+	c.Find(&service)
+
+	timeout := Opts.DestroyTimeout
+	Opts.DestroyTimeout = 50 * time.Millisecond
+	defer func() { Opts.DestroyTimeout = timeout }()
+
+	err := c.Shutdown(context.Background())
+	assert.Equal(t, []string{"service", "repo", "db"}, destroyed)
+
+	var fe *Error
+	if assert.ErrorAs(t, err, &fe) {
+		assert.Len(t, fe.Errors, 2)
+	}
+	assert.ErrorContains(t, err, "destroy *factory.lifecycleService err: stop failed")
+	assert.ErrorContains(t, err, "destroy *factory.lifecycleSlow err: timeout: 50ms")
+
+	assert.NoError(t, c.Shutdown(context.Background()))
+}
+
+func TestShutdownDestroyTimeout(t *testing.T) {
+	c := NewContainer()
+	c.Singleton(new(lifecycleSlow)).DestroyTimeout(20 * time.Millisecond)
+
+	var slow *lifecycleSlow
+	c.Find(&slow)
+
+	// its own timeout instead of Opts.DestroyTimeout
+	assert.EqualError(t, c.Shutdown(context.Background()), "1 error(s) decoding:\n\n* destroy *factory.lifecycleSlow err: timeout: 20ms")
+}
+
+func TestDestroyOrder(t *testing.T) {
+	items := make([]*contextCachedItem, 4)
+	created := make([]*createdObject, 4)
+	for i := range items {
+		items[i] = &contextCachedItem{}
+		created[i] = &createdObject{cci: items[i], obj: i}
+	}
+
+	// 1 depends on 2, 2 on 0, and 0 and 3 on each other
+	dependencies := map[*contextCachedItem]map[*contextCachedItem]struct{}{
+		items[2]: {items[0]: {}},
+		items[1]: {items[2]: {}},
+		items[3]: {items[0]: {}},
+		items[0]: {items[3]: {}},
+	}
+
+	var order []any
+	for _, co := range destroyOrder(created, dependencies) {
+		order = append(order, co.obj)
+	}
+	// the cycle is reached from 3, the latest one
+	assert.Equal(t, []any{1, 2, 0, 3}, order)
+}
+
+var started []string
+
+type startRepo struct{}
+
+func (r *startRepo) Init() {
+	started = append(started, "repo")
+}
+
+type startService struct {
+	Repo *startRepo `wire:"auto"`
+}
+
+func (s *startService) Init() {
+	started = append(started, "service")
+}
+
+type startBroken struct {
+	Missing *lifecycleDB `wire:"auto"`
+}
+
+type startLazy struct{}
+
+func (l *startLazy) Init() {
+	started = append(started, "lazy")
+}
+
+func TestStart(t *testing.T) {
+	started = nil
+
+	c := NewContainer()
+	c.Singleton(new(startService))
+	c.Singleton(new(startRepo))
+	c.Singleton(new(startLazy)).Lazy(true)
+
+	assert.NoError(t, c.Start(context.Background()))
+	assert.Equal(t, []string{"repo", "service"}, started)
+
+	broken := func() *Container {
+		c := NewContainer()
+		c.Singleton(new(startBroken))
+		c.NamedSingleton("broken", new(startBroken))
+		return c
+	}
+
+	err := broken().Start(context.Background())
+	assert.ErrorContains(t, err, "start *factory.startBroken err: use type to get Getter")
+	var fe *Error
+	assert.False(t, errors.As(err, &fe))
+
+	err = broken().StartCollect(context.Background())
+	if assert.ErrorAs(t, err, &fe) {
+		assert.Len(t, fe.Errors, 2)
+	}
+}
+
+type startInterface interface {
+	Name() string
+}
+
+type startNamed struct{}
+
+func (n *startNamed) Name() string {
+	started = append(started, "interface")
+	return "named"
+}
+
+func TestStartInterface(t *testing.T) {
+	started = nil
+
+	c := NewContainer()
+	c.Interface(new(startInterface)).SetInitFunc(func() any {
+		n := &startNamed{}
+		n.Name()
+		return n
+	})
+
+	assert.NoError(t, c.Start(context.Background()))
+	assert.Equal(t, []string{"interface"}, started)
+}
+
+func TestStartFailed(t *testing.T) {
+	c := NewContainer()
+	c.Singleton(new(startBroken))
+
+	err := c.Start(context.Background())
+	assert.ErrorContains(t, err, "use type to get Getter")
+
+	// not the half built object
+	var broken *startBroken
+	for i := 0; i < 2; i++ {
+		assert.ErrorContains(t, c.TryFind(&broken), "use type to get Getter")
+		assert.Nil(t, broken)
+	}
+}
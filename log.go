@@ -9,6 +9,20 @@ type Logger interface {
 	Debugf(template string, args ...any)
 }
 
+// ErrorLogger is implemented by the Loggers logging errors at the error level, such as the default one.
+// Otherwise the errors are logged by Debugf.
+type ErrorLogger interface {
+	Errorf(template string, args ...any)
+}
+
+func logErrorf(template string, args ...any) {
+	if l, ok := Opts.Log.(ErrorLogger); ok {
+		l.Errorf(template, args...)
+	} else {
+		Opts.Log.Debugf(template, args...)
+	}
+}
+
 type logger struct {
 	mu   sync.Mutex
 	hook func(msg string)
@@ -24,3 +38,7 @@ func (l *logger) Debugf(template string, args ...any) {
 		l.hook(msg)
 	}
 }
+
+func (l *logger) Errorf(template string, args ...any) {
+	l.Debugf("ERROR "+template, args...)
+}
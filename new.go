@@ -21,10 +21,12 @@ var newCtxMap = make(map[int]context.Context)
 var newCtxMapLock = sync.NewRWMutex()
 
 type Option struct {
-	useConstructor bool
-	initMethodName string
-	initParams     []string
-	lock           sync.Mutex
+	useConstructor    bool
+	initMethodName    string
+	initParams        []string
+	destroyMethodName string
+	destroyTimeout    time.Duration
+	lock              sync.Mutex
 }
 
 func NewOption() *Option {
@@ -70,14 +72,59 @@ func (o *Option) InitParams(initParams ...string) *Option {
 	return o
 }
 
+// DestroyMethodName sets the method called when the object is destroyed,
+// see DefaultDestroyMethodName for the methods used when it's empty.
+func (o *Option) DestroyMethodName(destroyMethodName string) *Option {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	o.destroyMethodName = destroyMethodName
+	return o
+}
+
+// DestroyTimeout sets the time given to destroy the object, 0 uses Opts.DestroyTimeout.
+func (o *Option) DestroyTimeout(destroyTimeout time.Duration) *Option {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	o.destroyTimeout = destroyTimeout
+	return o
+}
+
+// assign copies the settings of option into o, it's used by the builders' WithOption.
+func (o *Option) assign(option *Option) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	o.useConstructor = option.useConstructor
+	o.initMethodName = option.initMethodName
+	o.initParams = option.initParams
+	o.destroyMethodName = option.destroyMethodName
+	o.destroyTimeout = option.destroyTimeout
+}
+
+// getInitMethodName returns the name of the init method of the struct type vte.
+func (o *Option) getInitMethodName(vte reflect.Type) string {
+	initMethodName := o.initMethodName
+	if len(initMethodName) == 0 {
+		initMethodName = DefaultInitMethodName
+	}
+	if o.useConstructor {
+		initMethodName = vte.Name()
+	}
+
+	// 确保方法的第一个字母为大写
+	return strings.ToTitle(initMethodName[:1]) + initMethodName[1:]
+}
+
 var newDefaultOption = NewOption()
 
 func New[T any]() *T {
-	return initWithOptionTimeout(new(T), newDefaultOption, Opts.Timeout, nil).(*T)
+	return initWithOptionTimeout(_context, new(T), newDefaultOption, Opts.Timeout, nil).(*T)
 }
 
 func NewWithOption[T any](option *Option) *T {
-	return initWithOptionTimeout(new(T), option, Opts.Timeout, nil).(*T)
+	return initWithOptionTimeout(_context, new(T), option, Opts.Timeout, nil).(*T)
 }
 
 func NewWithFunc[T any](createFunc func() *T, beforeInit func(*T)) *T {
@@ -92,7 +139,7 @@ func NewWithFuncOption[T any](createFunc func() *T, beforeInit func(*T), option
 		t = new(T)
 	}
 
-	return initWithOptionTimeout(t, option, Opts.Timeout, func() {
+	return initWithOptionTimeout(_context, t, option, Opts.Timeout, func() {
 		if beforeInit != nil {
 			beforeInit(t)
 		}
@@ -105,22 +152,24 @@ func NewBeforeInit[T any](beforeInit func(*T)) *T {
 
 func NewBeforeInitOption[T any](beforeInit func(*T), option *Option) *T {
 	t := new(T)
-	return initWithOptionTimeout(t, option, Opts.Timeout, func() {
+	return initWithOptionTimeout(_context, t, option, Opts.Timeout, func() {
 		if beforeInit != nil {
 			beforeInit(t)
 		}
 	}).(*T)
 }
 
-func initWithOptionTimeout(t any, option *Option, timeout time.Duration, beforeInit func()) any {
+func initWithOptionTimeout(c *factoryContext, t any, option *Option, timeout time.Duration, beforeInit func()) any {
 	goId := sync.GoId()
 	newCtxMapLock.RLock()
 
 	ctx, loaded := newCtxMap[goId]
 	newCtxMapLock.RUnlock()
 
-	if !loaded {
-		ctx = initTypeCtx(getTimeoutContext(timeout))
+	if loaded {
+		ctx = withContainer(ctx, c)
+	} else {
+		ctx = initTypeCtx(c.timeoutContext(timeout))
 
 		newCtxMapLock.Lock()
 		newCtxMap[goId] = ctx
@@ -147,18 +196,7 @@ func initWithOptionContext(t any, ctx context.Context, option *Option, beforeIni
 
 	if vt.Kind() == reflect.Ptr && vt.Elem().Kind() == reflect.Struct {
 		vte := vt.Elem()
-
-		// get init method name
-		initMethodName := option.initMethodName
-		if len(initMethodName) == 0 {
-			initMethodName = DefaultInitMethodName
-		}
-		if option.useConstructor {
-			initMethodName = vte.Name()
-		}
-
-		// 确保方法的第一个字母为大写
-		initMethodName = strings.ToTitle(initMethodName[:1]) + initMethodName[1:]
+		initMethodName := option.getInitMethodName(vte)
 
 		// from name get method
 		initMethod, ok := vt.MethodByName(initMethodName)
@@ -172,7 +210,7 @@ func initWithOptionContext(t any, ctx context.Context, option *Option, beforeIni
 				// defer 将init的调用放到auto wire之后
 				defer initMethod.Func.Call(append([]reflect.Value{reflect.ValueOf(t)}, params...))
 			} else {
-				panic(fmt.Errorf("create %s error: %v", vte.Name(), err))
+				panic(newResolveError(ErrInitFailed, err, "create %s error: %v", vte.Name(), err))
 			}
 		}
 	} else {
@@ -181,7 +219,7 @@ func initWithOptionContext(t any, ctx context.Context, option *Option, beforeIni
 
 	// do auto wire
 	if err := autoWireContext(ctx, t); err != nil {
-		panic(fmt.Errorf("create %s error: %v", vt.Elem().Name(), err))
+		panic(newResolveError(ErrInitFailed, err, "create %s error: %v", vt.Elem().Name(), err))
 	}
 
 	if beforeInit != nil {
@@ -200,7 +238,7 @@ func _getMethodParams(ctx context.Context, self any, methodType reflect.Type, me
 		for i := 1; i < methodType.NumIn(); i++ {
 			paramType := methodType.In(i)
 			if (paramType.Kind() == reflect.Ptr && paramType.Elem().Kind() == reflect.Struct) || paramType.Kind() == reflect.Interface {
-				params = append(params, reflect.ValueOf(_context.getByType(ctx, paramType)))
+				params = append(params, reflect.ValueOf(getContextContainer(ctx).getByType(ctx, paramType)))
 			} else {
 				return nil, fmt.Errorf("method %s's %d argument must be a struct point or an interface", methodName, i)
 			}
@@ -214,12 +252,16 @@ func _getMethodParams(ctx context.Context, self any, methodType reflect.Type, me
 				return nil, fmt.Errorf("method %s's %d argument tag is err: %v", methodName, i+baseIndex, err)
 			}
 
-			v, err := getValueByWireTag(ctx, self, tagValue, paramType)
+			v, found, err := getOptionalValueByWireTag(ctx, self, tagValue, paramType)
 			if err != nil {
-				return nil, fmt.Errorf("method %s's %d argument get value from tag err: %v", methodName, i+baseIndex, err)
+				return nil, fmt.Errorf("method %s's %d argument get value from tag err: %w", methodName, i+baseIndex, err)
 			}
 
-			params = append(params, reflect.ValueOf(v))
+			if found {
+				params = append(params, reflect.ValueOf(v))
+			} else {
+				params = append(params, reflect.Zero(paramType))
+			}
 		}
 	} else {
 		return nil, errors.New("init params count must equals with method params count")
@@ -0,0 +1,66 @@
+package factory
+
+import (
+	"errors"
+	"github.com/expgo/sync"
+	"time"
+)
+
+// errBuilding is the failure of an object still being built after its build timed out.
+var errBuilding = errors.New("still building")
+
+// buildOnce builds an object once. The once is done even if the build fails, so the failure is kept,
+// and the later lookups fail with it instead of getting the object half built.
+type buildOnce struct {
+	once    sync.Once
+	lock    sync.Mutex
+	failure any // the panic of the build, errBuilding while it's running
+}
+
+func newBuildOnce() *buildOnce {
+	return &buildOnce{
+		once: sync.NewOnce(),
+		lock: sync.NewMutex(),
+	}
+}
+
+// do calls build once, and panics with its failure on every call. timeoutErr makes the error of timeout,
+// an object built after its timeout is used by the later calls.
+func (b *buildOnce) do(timeout time.Duration, build func(), timeoutErr func(err error) error) {
+	err := b.once.DoTimeout(timeout, func() error {
+		b.setFailure(errBuilding)
+
+		defer func() {
+			if r := recover(); r != nil {
+				b.setFailure(r)
+				panic(r)
+			}
+		}()
+
+		build()
+		b.setFailure(nil)
+
+		return nil
+	})
+
+	b.lock.Lock()
+	if err != nil && b.failure == errBuilding {
+		b.failure = timeoutErr(err)
+	}
+	failure := b.failure
+	b.lock.Unlock()
+
+	if failure == errBuilding {
+		panic(timeoutErr(errBuilding))
+	}
+	if failure != nil {
+		panic(failure)
+	}
+}
+
+func (b *buildOnce) setFailure(failure any) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.failure = failure
+}
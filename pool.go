@@ -5,31 +5,43 @@ import (
 	"sync"
 )
 
-var _poolCache = map[reflect.Type]*sync.Pool{}
-var _poolCacheLock = &sync.RWMutex{}
+type poolCache struct {
+	pools   map[reflect.Type]*sync.Pool
+	options map[reflect.Type]*Option
+	lock    *sync.RWMutex
+}
+
+func newPoolCache() *poolCache {
+	return &poolCache{
+		pools:   map[reflect.Type]*sync.Pool{},
+		options: map[reflect.Type]*Option{},
+		lock:    &sync.RWMutex{},
+	}
+}
 
-func getPoolByType(vt reflect.Type) *sync.Pool {
-	_poolCacheLock.RLock()
-	pool, ok := _poolCache[vt]
-	_poolCacheLock.RUnlock()
+func (c *factoryContext) getPoolByType(vt reflect.Type) *sync.Pool {
+	c.pools.lock.RLock()
+	pool, ok := c.pools.pools[vt]
+	c.pools.lock.RUnlock()
 
 	if !ok {
 		pool = &sync.Pool{
 			New: func() interface{} {
-				return initWithOptionTimeout(reflect.New(vt).Interface(), newDefaultOption, Opts.Timeout, nil)
+				return initWithOptionTimeout(c, reflect.New(vt).Interface(), newDefaultOption, Opts.Timeout, nil)
 			},
 		}
 
-		_poolCacheLock.Lock()
-		_poolCache[vt] = pool
-		_poolCacheLock.Unlock()
+		c.pools.lock.Lock()
+		c.pools.pools[vt] = pool
+		c.pools.options[vt] = newDefaultOption
+		c.pools.lock.Unlock()
 	}
 
 	return pool
 }
 
 func Get[T any]() *T {
-	return getPoolByType(reflect.TypeOf((*T)(nil)).Elem()).Get().(*T)
+	return _context.getPoolByType(reflect.TypeOf((*T)(nil)).Elem()).Get().(*T)
 }
 
 func Put[T any](t *T) {
@@ -37,22 +49,23 @@ func Put[T any](t *T) {
 		return
 	}
 
-	getPoolByType(reflect.TypeOf((*T)(nil)).Elem()).Put(t)
+	_context.getPoolByType(reflect.TypeOf((*T)(nil)).Elem()).Put(t)
 }
 
 func SetPoolInit[T any](option *Option) {
-	setPoolInitWithType(reflect.TypeOf((*T)(nil)).Elem(), option)
+	_context.setPoolInitWithType(reflect.TypeOf((*T)(nil)).Elem(), option)
 }
 
-func setPoolInitWithType(vt reflect.Type, option *Option) {
+func (c *factoryContext) setPoolInitWithType(vt reflect.Type, option *Option) {
 	pool := &sync.Pool{
 		New: func() interface{} {
-			return initWithOptionTimeout(reflect.New(vt).Interface(), option, Opts.Timeout, nil)
+			return initWithOptionTimeout(c, reflect.New(vt).Interface(), option, Opts.Timeout, nil)
 		},
 	}
 
-	_poolCacheLock.Lock()
-	defer _poolCacheLock.Unlock()
+	c.pools.lock.Lock()
+	defer c.pools.lock.Unlock()
 
-	_poolCache[vt] = pool
+	c.pools.pools[vt] = pool
+	c.pools.options[vt] = option
 }
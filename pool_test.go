@@ -2,8 +2,6 @@ package factory
 
 import (
 	"github.com/stretchr/testify/assert"
-	"reflect"
-	"sync"
 	"testing"
 )
 
@@ -59,7 +57,7 @@ func TestPut(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			// cleanup pool
-			_poolCache = map[reflect.Type]*sync.Pool{}
+			_context.pools = newPoolCache()
 
 			Put(test.input)
 
@@ -0,0 +1,243 @@
+package factory
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// registration puts a singleton into the builders of its container. It's deferred until the chain setting up
+// the singleton is done, such as Singleton[T]().Name("store").Profile("dev"), which is seen as the next time
+// the container registers or looks up a builder, so the singletons of inactive profiles never get into the builders.
+type registration struct {
+	context    *factoryContext
+	cci        *contextCachedItem
+	profiles   []string // registered only when one of them is active, always if it's empty
+	typed      bool
+	name       string
+	pending    bool // waiting for flushRegistrations
+	registered bool
+}
+
+// ActivateProfiles adds profiles to the active ones of the default container, see Container.ActivateProfiles.
+func ActivateProfiles(profiles ...string) {
+	_context.activateProfiles(profiles)
+}
+
+// ProfileActive reports whether one of profiles is active in the default container.
+func ProfileActive(profiles ...string) bool {
+	return _context.profileActive(profiles)
+}
+
+func splitProfiles(value string) (profiles []string) {
+	for _, profile := range strings.Split(value, ",") {
+		if profile = strings.TrimSpace(profile); len(profile) > 0 {
+			profiles = append(profiles, profile)
+		}
+	}
+	return
+}
+
+// activateProfiles adds profiles to the ones activated in c, the registrations of c and its children matching them
+// are put into their builders.
+func (c *factoryContext) activateProfiles(profiles []string) {
+	func() {
+		c.profileLock.Lock()
+		defer c.profileLock.Unlock()
+
+		for _, profile := range profiles {
+			profile = strings.TrimSpace(profile)
+			if len(profile) > 0 && !c.isProfileActive(profile) {
+				c.profiles = append(c.profiles, profile)
+			}
+		}
+	}()
+
+	c.applyProfiles()
+}
+
+// applyProfiles registers or removes the registrations of c and its children by the active profiles.
+func (c *factoryContext) applyProfiles() {
+	func() {
+		c.profileLock.Lock()
+		defer c.profileLock.Unlock()
+
+		for _, r := range c.profileEntries {
+			r.apply()
+		}
+	}()
+
+	for _, child := range c.getChildren() {
+		child.applyProfiles()
+	}
+}
+
+func (c *factoryContext) profileActive(profiles []string) bool {
+	c.profileLock.Lock()
+	defer c.profileLock.Unlock()
+
+	return c.isProfileActive(profiles...)
+}
+
+// isProfileActive reports whether one of profiles is activated in c or its parents, c.profileLock must be held.
+func (c *factoryContext) isProfileActive(profiles ...string) bool {
+	if hasProfile(c.profiles, profiles) {
+		return true
+	}
+
+	for fc := c.parent; fc != nil; fc = fc.parent {
+		fc.profileLock.Lock()
+		active := hasProfile(fc.profiles, profiles)
+		fc.profileLock.Unlock()
+
+		if active {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasProfile reports whether one of profiles is in active.
+func hasProfile(active []string, profiles []string) bool {
+	for _, profile := range profiles {
+		for _, a := range active {
+			if profile == a {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *factoryContext) newRegistration(cci *contextCachedItem) *registration {
+	return &registration{context: c, cci: cci}
+}
+
+// flushRegistrations puts the pending registrations into the builders of c,
+// it's called before the builders of c are registered or looked up.
+func (c *factoryContext) flushRegistrations() {
+	if atomic.LoadInt32(&c.pendingRegistrations) == 0 {
+		return
+	}
+
+	c.profileLock.Lock()
+	defer c.profileLock.Unlock()
+
+	for len(c.registrations) > 0 {
+		r := c.registrations[0]
+		c.registrations = c.registrations[1:]
+		atomic.AddInt32(&c.pendingRegistrations, -1)
+
+		r.pending = false
+		r.apply()
+	}
+}
+
+// setType registers r by its type.
+func (r *registration) setType() {
+	r.context.profileLock.Lock()
+	defer r.context.profileLock.Unlock()
+
+	r.typed = true
+	if r.registered {
+		r.context.putByType(r.cci._type, r.cci)
+	} else {
+		r.queue()
+	}
+}
+
+// setName registers r by name.
+func (r *registration) setName(name string) {
+	r.context.profileLock.Lock()
+	defer r.context.profileLock.Unlock()
+
+	if _, ok := r.context.lookupExprVar(name); ok {
+		// not depending on the profiles, so it's not deferred
+		panic(fmt.Errorf("Named builder %s collides with the expr var", name))
+	}
+
+	r.name = name
+	if r.registered {
+		r.context.putByName(name, r.cci)
+	} else {
+		r.queue()
+	}
+}
+
+// setProfiles makes r only registered when one of profiles is active. If r is flushed already,
+// which means Profile isn't called in the chain registering it, it's removed when they're inactive.
+func (r *registration) setProfiles(profiles []string) {
+	r.context.profileLock.Lock()
+	defer r.context.profileLock.Unlock()
+
+	if len(r.profiles) > 0 {
+		panic("profile already set")
+	}
+
+	for _, profile := range profiles {
+		if profile = strings.TrimSpace(profile); len(profile) > 0 {
+			r.profiles = append(r.profiles, profile)
+		}
+	}
+	if len(r.profiles) == 0 {
+		panic("profile must not be empty")
+	}
+
+	r.context.profileEntries = append(r.context.profileEntries, r)
+	r.apply()
+}
+
+// queue adds r to the pending registrations of its container, c.profileLock must be held.
+func (r *registration) queue() {
+	if !r.pending {
+		r.pending = true
+		r.context.registrations = append(r.context.registrations, r)
+		atomic.AddInt32(&r.context.pendingRegistrations, 1)
+	}
+}
+
+// apply registers r when one of its profiles is active, and removes it when none is, c.profileLock must be held.
+func (r *registration) apply() {
+	if r.pending {
+		// flushed later
+		return
+	}
+
+	active := len(r.profiles) == 0 || r.context.isProfileActive(r.profiles...)
+	if active == r.registered {
+		return
+	}
+
+	if active {
+		if r.typed {
+			r.context.putByType(r.cci._type, r.cci)
+		}
+		if len(r.name) > 0 {
+			r.context.putByName(r.name, r.cci)
+		}
+	} else {
+		r.context.removeItem(r.cci)
+	}
+
+	r.registered = active
+}
+
+// removeItem removes cci from the typed and named builders of c.
+func (c *factoryContext) removeItem(cci *contextCachedItem) {
+	c.typedMapLock.Lock()
+	for k, v := range c.typedMap {
+		if v == cci {
+			delete(c.typedMap, k)
+		}
+	}
+	c.typedMapLock.Unlock()
+
+	c.namedMapLock.Lock()
+	for k, v := range c.namedMap {
+		if v == cci {
+			delete(c.namedMap, k)
+		}
+	}
+	c.namedMapLock.Unlock()
+}
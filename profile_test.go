@@ -0,0 +1,111 @@
+package factory
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type profileFake struct{}
+
+type profileReal struct{}
+
+func TestProfile(t *testing.T) {
+	profiles := Opts.Profiles
+	Opts.Profiles = []string{"dev"}
+	defer func() {
+		Opts.Profiles = profiles
+	}()
+
+	c := NewContainer()
+	c.NamedSingleton("store", new(profileReal)).Profile("prod")
+	c.NamedSingleton("store", new(profileFake)).Profile("dev", "test")
+	c.Singleton(new(profileReal)).Profile("prod")
+	c.Singleton(new(profileFake)).Name("fake").Profile("test")
+
+	assert.True(t, c.ProfileActive("dev"))
+	assert.False(t, c.ProfileActive("prod", "test"))
+
+	var fake *profileFake
+	c.FindByName("store", &fake)
+	assert.NotNil(t, fake)
+
+	var real *profileReal
+	assert.ErrorIs(t, c.TryFind(&real), ErrNotFound)
+	assert.ErrorIs(t, c.TryFindByName("fake", &fake), ErrNotFound)
+
+	c.ActivateProfiles("test")
+	assert.True(t, c.ProfileActive("test"))
+	assert.Nil(t, c.TryFindByName("fake", &fake))
+	assert.ErrorIs(t, c.TryFind(&real), ErrNotFound)
+
+	// only activated in c
+	assert.Equal(t, []string{"dev"}, Opts.Profiles)
+	assert.False(t, NewContainer().ProfileActive("test"))
+}
+
+func TestProfileOrder(t *testing.T) {
+	// the active one first
+	c := NewContainer()
+	c.ActivateProfiles("dev")
+	c.NamedSingleton("store", new(profileFake)).Profile("dev")
+	c.NamedSingleton("store", new(profileReal)).Profile("prod")
+	c.Singleton(new(profileFake)).Profile("dev")
+	c.Singleton(new(profileFake)).Profile("prod")
+
+	var fake *profileFake
+	c.FindByName("store", &fake)
+	assert.NotNil(t, fake)
+
+	// the inactive one first
+	c = NewContainer()
+	c.ActivateProfiles("dev")
+	c.NamedSingleton("store", new(profileReal)).Profile("prod")
+	c.NamedSingleton("store", new(profileFake)).Profile("dev")
+	c.Singleton(new(profileFake)).Profile("prod")
+	c.Singleton(new(profileFake)).Profile("dev")
+
+	c.FindByName("store", &fake)
+	assert.NotNil(t, fake)
+	assert.Nil(t, c.TryFind(&fake))
+
+	// both active
+	assert.PanicsWithError(t, "Named builder allready exist: store", func() {
+		c.ActivateProfiles("prod")
+	})
+}
+
+func TestProfileChild(t *testing.T) {
+	parent := NewContainer()
+	child := parent.Child()
+	child.Singleton(new(profileFake)).Profile("dev")
+
+	var fake *profileFake
+	assert.ErrorIs(t, child.TryFind(&fake), ErrNotFound)
+
+	// activated in the parent after the child registered it
+	parent.ActivateProfiles("dev")
+	assert.True(t, child.ProfileActive("dev"))
+	assert.Nil(t, child.TryFind(&fake))
+	assert.False(t, NewContainer().ProfileActive("dev"))
+}
+
+func TestProfileAfterRegistered(t *testing.T) {
+	c := NewContainer()
+	s := c.Singleton(new(profileFake))
+
+	var fake *profileFake
+	assert.Nil(t, c.TryFind(&fake))
+
+	// out of the chain, it's removed
+	s.Profile("prod")
+	assert.ErrorIs(t, c.TryFind(&fake), ErrNotFound)
+
+	assert.PanicsWithValue(t, "profile already set", func() {
+		s.Profile("dev")
+	})
+}
+
+func TestSplitProfiles(t *testing.T) {
+	assert.Equal(t, []string{"dev", "test"}, splitProfiles(" dev, ,test"))
+	assert.Nil(t, splitProfiles(""))
+}
@@ -0,0 +1,113 @@
+package factory
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	// EnvTagName is the tag of the key a field is bound to by ConfigProperties.
+	EnvTagName = "env"
+	// EnvPrefixTagName is the tag of the prefix added to the keys of a nested struct field.
+	EnvPrefixTagName = "envPrefix"
+	// DefaultTagName is the tag of the value used when the key isn't set.
+	DefaultTagName = "default"
+	// RequiredTagName is the tag marks the key must be set, such as `required:"true"`.
+	RequiredTagName = "required"
+)
+
+// ConfigProperties registers *T as a singleton bound from the config, see Container.ConfigProperties.
+func ConfigProperties[T any](prefix string) *singleton {
+	return _context.configProperties(reflect.TypeOf((*T)(nil)), prefix)
+}
+
+func (c *factoryContext) configProperties(vt reflect.Type, prefix string) *singleton {
+	s := _singletonWithType(c, vt).setType()
+
+	return s.SetInitFunc(func() any {
+		result := reflect.New(vt.Elem())
+		if err := bindProperties(result, prefix, c.lookupConfig); err != nil {
+			panic(err)
+		}
+
+		return result.Interface()
+	})
+}
+
+// bindProperties sets the fields of the *struct v from the values got by lookup, the keys are the 'env' tags with prefix.
+// The fields of nested structs are bound with the prefix added by their 'envPrefix' tags.
+// All keys which are required but missing, and the values can't be converted are returned together as an *Error.
+func bindProperties(v reflect.Value, prefix string, lookup func(key string) (string, bool)) error {
+	var errs []string
+	bindStruct(v.Elem(), prefix, lookup, &errs)
+
+	if len(errs) > 0 {
+		return &Error{Errors: errs}
+	}
+
+	return nil
+}
+
+func bindStruct(v reflect.Value, prefix string, lookup func(key string) (string, bool), errs *[]string) {
+	vt := v.Type()
+
+	for i := 0; i < vt.NumField(); i++ {
+		field := vt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		fieldValue := v.Field(i)
+		key, hasKey := field.Tag.Lookup(EnvTagName)
+
+		if !hasKey && isNestedProperties(field.Type) {
+			fieldPrefix := prefix + field.Tag.Get(EnvPrefixTagName)
+			if field.Type.Kind() == reflect.Ptr {
+				if fieldValue.IsNil() {
+					fieldValue.Set(reflect.New(field.Type.Elem()))
+				}
+				fieldValue = fieldValue.Elem()
+			}
+			bindStruct(fieldValue, fieldPrefix, lookup, errs)
+			continue
+		}
+
+		key = strings.TrimSpace(key)
+		if len(key) == 0 {
+			continue
+		}
+		key = prefix + key
+
+		value, ok := lookup(key)
+		if !ok {
+			if value, ok = field.Tag.Lookup(DefaultTagName); !ok {
+				if required, _ := strconv.ParseBool(field.Tag.Get(RequiredTagName)); required {
+					*errs = append(*errs, fmt.Sprintf("required key %s of %s.%s is missing", key, vt.String(), field.Name))
+				}
+				continue
+			}
+		}
+
+		converted, err := convertValue(value, field.Type, field.Tag.Get(LayoutTagName))
+		if err != nil {
+			*errs = append(*errs, fmt.Sprintf("key %s of %s.%s convert err: %v", key, vt.String(), field.Name, err))
+			continue
+		}
+		fieldValue.Set(reflect.ValueOf(converted))
+	}
+}
+
+// isNestedProperties reports whether the fields of t, a struct or a *struct, are bound one by one.
+func isNestedProperties(t reflect.Type) bool {
+	if isConvertible(t) {
+		return false
+	}
+
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t.Kind() == reflect.Struct && !isConvertible(t)
+}
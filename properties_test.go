@@ -0,0 +1,63 @@
+package factory
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type propertiesDB struct {
+	Host    string        `env:"HOST" required:"true"`
+	Port    int           `env:"PORT" default:"5432"`
+	Timeout time.Duration `env:"TIMEOUT" default:"1s"`
+}
+
+type propertiesApp struct {
+	Name     string        `env:"NAME"`
+	Tags     []string      `env:"TAGS"`
+	Debug    bool          `env:"DEBUG" default:"false"`
+	Interval unmarshaler   `env:"INTERVAL"`
+	DB       propertiesDB  `envPrefix:"DB_"`
+	Cache    *propertiesDB `envPrefix:"CACHE_"`
+	ignored  string        `env:"IGNORED"`
+}
+
+func TestConfigProperties(t *testing.T) {
+	t.Setenv("PROPS_NAME", "app")
+	t.Setenv("PROPS_TAGS", "a,b")
+	t.Setenv("PROPS_INTERVAL", "2m")
+	t.Setenv("PROPS_DB_HOST", "db.local")
+	t.Setenv("PROPS_DB_TIMEOUT", "5s")
+	t.Setenv("PROPS_CACHE_HOST", "cache.local")
+	t.Setenv("PROPS_CACHE_PORT", "6379")
+
+	c := NewContainer()
+	c.ConfigProperties(new(propertiesApp), "PROPS_")
+
+	var app *propertiesApp
+	c.Find(&app)
+
+	assert.Equal(t, "app", app.Name)
+	assert.Equal(t, []string{"a", "b"}, app.Tags)
+	assert.False(t, app.Debug)
+	assert.Equal(t, 2*time.Minute, app.Interval.Duration)
+	assert.Equal(t, propertiesDB{Host: "db.local", Port: 5432, Timeout: 5 * time.Second}, app.DB)
+	assert.Equal(t, &propertiesDB{Host: "cache.local", Port: 6379, Timeout: time.Second}, app.Cache)
+}
+
+func TestConfigPropertiesRequired(t *testing.T) {
+	t.Setenv("MISSING_DB_PORT", "abc")
+
+	c := NewContainer()
+	c.ConfigProperties(new(propertiesApp), "MISSING_")
+
+	var app *propertiesApp
+	err := c.TryFind(&app)
+	assert.ErrorIs(t, err, ErrInitFailed)
+
+	var e *Error
+	assert.ErrorAs(t, err, &e)
+	assert.Len(t, e.Errors, 3)
+	assert.Contains(t, e.Errors, "required key MISSING_DB_HOST of factory.propertiesDB.Host is missing")
+	assert.Contains(t, e.Errors, "required key MISSING_CACHE_HOST of factory.propertiesDB.Host is missing")
+}
@@ -0,0 +1,112 @@
+package factory
+
+import (
+	"context"
+	"github.com/expgo/sync"
+	"reflect"
+	"strings"
+)
+
+// prototype builds a new object on every resolution, it's never shared between consumers.
+type prototype struct {
+	option Option
+
+	_name   string
+	lock    sync.Mutex
+	context *factoryContext
+
+	cci *contextCachedItem
+}
+
+func _prototypeWithType(c *factoryContext, vt reflect.Type) *prototype {
+	result := &prototype{
+		lock:    sync.NewMutex(),
+		context: c,
+		option: Option{
+			lock: sync.NewMutex(),
+		},
+	}
+
+	result.cci = &contextCachedItem{context: c}
+
+	result.cci._type = vt
+	result.cci.option = &result.option
+	result.cci.kind = itemKindPrototype
+
+	result.cci.getter = func(ctx context.Context) any {
+		return result.getWithContext(ctx)
+	}
+
+	return result
+}
+
+func Prototype[T any]() *prototype {
+	return _prototypeWithType(_context, reflect.TypeOf((*T)(nil))).setType()
+}
+
+func NamedPrototype[T any](name string) *prototype {
+	return _prototypeWithType(_context, reflect.TypeOf((*T)(nil))).Name(name)
+}
+
+func (p *prototype) setType() *prototype {
+	p.context.setByType(p.cci._type, p.cci)
+	return p
+}
+
+func (p *prototype) Name(name string) *prototype {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	name = strings.TrimSpace(name)
+	if len(name) == 0 {
+		panic("name must not be empty")
+	}
+
+	if len(p._name) == 0 {
+		p.context.setByName(name, p.cci)
+		p._name = name
+	} else {
+		panic("name already set")
+	}
+
+	return p
+}
+
+func (p *prototype) WithOption(option *Option) *prototype {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if option != nil {
+		p.option.assign(option)
+	}
+
+	return p
+}
+
+func (p *prototype) UseConstructor(useConstructor bool) *prototype {
+	p.option.UseConstructor(useConstructor)
+	return p
+}
+
+func (p *prototype) InitMethodName(initMethodName string) *prototype {
+	p.option.InitMethodName(initMethodName)
+	return p
+}
+
+func (p *prototype) InitParams(initParams ...string) *prototype {
+	p.option.InitParams(initParams...)
+	return p
+}
+
+// Order sets the position of the prototype in the slices injected by 'wire:"all"', the lower ones come first.
+func (p *prototype) Order(order int) *prototype {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.cci.order = order
+	return p
+}
+
+func (p *prototype) getWithContext(ctx context.Context) any {
+	return initWithOptionContext(reflect.New(p.cci._type.Elem()).Interface(), withContainer(withoutScope(getNextTimeoutContext(ctx)), p.context), &p.option, nil)
+}
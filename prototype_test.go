@@ -0,0 +1,41 @@
+package factory
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type prototypeCounter struct {
+	Count int
+	Repo  *containerRepo `wire:"auto"`
+}
+
+func (p *prototypeCounter) Init(repo *containerRepo) {
+	p.Count++
+}
+
+type prototypeConsumer struct {
+	ByType *prototypeCounter `wire:"auto"`
+	ByName *prototypeCounter `wire:"name:counter"`
+}
+
+func TestPrototype(t *testing.T) {
+	c := NewContainer()
+	c.Singleton(new(containerRepo))
+	c.Prototype(new(prototypeCounter)).Name("counter")
+
+	var c1, c2 *prototypeConsumer
+	c.New(&c1)
+	c.New(&c2)
+
+	assert.NotSame(t, c1.ByType, c1.ByName)
+	assert.NotSame(t, c1.ByType, c2.ByType)
+	assert.Same(t, c1.ByType.Repo, c2.ByName.Repo)
+	assert.Equal(t, 1, c1.ByType.Count)
+	assert.Equal(t, 1, c2.ByName.Count)
+
+	var p1, p2 *prototypeCounter
+	c.Find(&p1)
+	c.Find(&p2)
+	assert.NotSame(t, p1, p2)
+}
@@ -0,0 +1,271 @@
+package factory
+
+import (
+	"context"
+	"fmt"
+	"github.com/expgo/sync"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// scope caches the objects of scoped builders, one object per builder,
+// until the scope ends.
+type scope struct {
+	entries map[*contextCachedItem]*scopeEntry
+	objs    []*createdObject // in creation order
+	closed  bool
+	lock    sync.Mutex
+}
+
+type scopeEntry struct {
+	once *buildOnce
+	obj  any
+}
+
+// WithScope returns a context holding a new scope. Scoped objects got with this context,
+// or wired while getting an object with this context, are built once and reused inside the scope.
+// The scope ends when cancel is called or the parent context is done, the objects it created are
+// released then, and destroyed like Shutdown does: closed if they implement io.Closer,
+// or their destroy method is called.
+func WithScope(parent context.Context) (ctx context.Context, cancel context.CancelFunc) {
+	s := &scope{
+		entries: make(map[*contextCachedItem]*scopeEntry),
+		lock:    sync.NewMutex(),
+	}
+
+	ctx, cancelCtx := context.WithCancel(context.WithValue(parent, ScopeKey, s))
+
+	go func() {
+		<-ctx.Done()
+		s.close()
+	}()
+
+	return ctx, func() {
+		cancelCtx()
+		s.close()
+	}
+}
+
+func getContextScope(ctx context.Context) *scope {
+	if value := ctx.Value(ScopeKey); value != nil {
+		return value.(*scope)
+	}
+	return nil
+}
+
+// withoutScope hides the scope of ctx, the singletons and prototypes are built with it,
+// so they never keep the scoped objects, which are destroyed when the scope ends.
+func withoutScope(ctx context.Context) context.Context {
+	if getContextScope(ctx) == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, ScopeKey, nil)
+}
+
+func (s *scope) get(ctx context.Context, cci *contextCachedItem, option *Option, build func() any) any {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		panic(fmt.Errorf("getting %s, scope already closed", cci._type.String()))
+	}
+
+	entry, ok := s.entries[cci]
+	if !ok {
+		entry = &scopeEntry{once: newBuildOnce()}
+		s.entries[cci] = entry
+	}
+	s.lock.Unlock()
+
+	timeout := getContextTimeout(ctx)
+	entry.once.do(timeout, func() {
+		entry.obj = build()
+		created := newCreatedObject(cci, entry.obj, option)
+
+		s.lock.Lock()
+		closed := s.closed
+		if !closed {
+			s.objs = append(s.objs, created)
+		}
+		s.lock.Unlock()
+
+		if closed {
+			// the scope ended while building, nobody else releases it
+			if err := destroyTimeout(context.Background(), created); err != nil {
+				Opts.Log.Debugf("scope close: %v", err)
+			}
+		}
+	}, func(err error) error {
+		return newResolveError(ErrTimeout, err, "[%s]init scoped %s, timeout: %s err: %+v", time.Now(), cci._type.String(), timeout, err)
+	})
+
+	return entry.obj
+}
+
+func (s *scope) close() {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		return
+	}
+
+	s.closed = true
+	objs := s.objs
+	s.objs = nil
+	s.entries = nil
+	s.lock.Unlock()
+
+	// release in the reverse order of creation
+	for i := len(objs) - 1; i >= 0; i-- {
+		if err := destroyTimeout(context.Background(), objs[i]); err != nil {
+			Opts.Log.Debugf("scope close: %v", err)
+		}
+	}
+}
+
+type scoped struct {
+	option Option
+
+	_name   string
+	lock    sync.Mutex
+	context *factoryContext
+
+	cci *contextCachedItem
+}
+
+func _scopedWithType(c *factoryContext, vt reflect.Type) *scoped {
+	result := &scoped{
+		lock:    sync.NewMutex(),
+		context: c,
+		option: Option{
+			lock: sync.NewMutex(),
+		},
+	}
+
+	result.cci = &contextCachedItem{context: c}
+
+	result.cci._type = vt
+	result.cci.option = &result.option
+	result.cci.kind = itemKindScoped
+
+	result.cci.getter = func(ctx context.Context) any {
+		return result.getWithContext(ctx)
+	}
+
+	return result
+}
+
+func Scoped[T any]() *scoped {
+	return _scopedWithType(_context, reflect.TypeOf((*T)(nil))).setType()
+}
+
+func NamedScoped[T any](name string) *scoped {
+	return _scopedWithType(_context, reflect.TypeOf((*T)(nil))).Name(name)
+}
+
+func (s *scoped) setType() *scoped {
+	s.context.setByType(s.cci._type, s.cci)
+	return s
+}
+
+func (s *scoped) Name(name string) *scoped {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	name = strings.TrimSpace(name)
+	if len(name) == 0 {
+		panic("name must not be empty")
+	}
+
+	if len(s._name) == 0 {
+		s.context.setByName(name, s.cci)
+		s._name = name
+	} else {
+		panic("name already set")
+	}
+
+	return s
+}
+
+func (s *scoped) WithOption(option *Option) *scoped {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if option != nil {
+		s.option.assign(option)
+	}
+
+	return s
+}
+
+func (s *scoped) UseConstructor(useConstructor bool) *scoped {
+	s.option.UseConstructor(useConstructor)
+	return s
+}
+
+func (s *scoped) InitMethodName(initMethodName string) *scoped {
+	s.option.InitMethodName(initMethodName)
+	return s
+}
+
+func (s *scoped) InitParams(initParams ...string) *scoped {
+	s.option.InitParams(initParams...)
+	return s
+}
+
+func (s *scoped) DestroyMethodName(destroyMethodName string) *scoped {
+	s.option.DestroyMethodName(destroyMethodName)
+	return s
+}
+
+// Order sets the position of the scoped builder in the slices injected by 'wire:"all"', the lower ones come first.
+func (s *scoped) Order(order int) *scoped {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.cci.order = order
+	return s
+}
+
+// DestroyTimeout sets the time given to destroy the objects when their scope ends, 0 uses Opts.DestroyTimeout.
+func (s *scoped) DestroyTimeout(destroyTimeout time.Duration) *scoped {
+	s.option.DestroyTimeout(destroyTimeout)
+	return s
+}
+
+func (s *scoped) getWithContext(ctx context.Context) any {
+	sc := getContextScope(ctx)
+	if sc == nil {
+		panic(newResolveError(ErrNoScope, nil, "scoped %s must be got within a scope, use WithScope to create one", s.cci._type.String()))
+	}
+
+	return sc.get(ctx, s.cci, &s.option, func() any {
+		return initWithOptionContext(reflect.New(s.cci._type.Elem()).Interface(), withContainer(getNextTimeoutContext(ctx), s.context), &s.option, nil)
+	})
+}
+
+// FindContext finds T with ctx, scoped objects are reused inside the scope held by ctx.
+func FindContext[T any](ctx context.Context) *T {
+	return getContextContainer(ctx).findContext(ctx, reflect.TypeOf((*T)(nil))).(*T)
+}
+
+// NewContext creates a new T with ctx, its scoped dependencies are reused inside the scope held by ctx.
+func NewContext[T any](ctx context.Context) *T {
+	return getContextContainer(ctx).newContext(ctx, new(T), newDefaultOption).(*T)
+}
+
+func (c *factoryContext) findContext(ctx context.Context, vt reflect.Type) any {
+	result := c.getByType(withContainer(ctx, c), vt)
+
+	resultType := reflect.TypeOf(result)
+	if resultType.Kind() == reflect.Ptr && resultType.ConvertibleTo(vt) {
+		return result
+	}
+
+	// panic
+	panic(fmt.Errorf("Invalid type: need %v, get %v", vt, resultType))
+}
+
+func (c *factoryContext) newContext(ctx context.Context, t any, option *Option) any {
+	return initWithOptionContext(t, initTypeCtx(withContainer(ctx, c)), option, nil)
+}
@@ -0,0 +1,161 @@
+package factory
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type scopedConn struct {
+	closed bool
+}
+
+func (c *scopedConn) Close() error {
+	c.closed = true
+	return nil
+}
+
+type scopedRepo struct {
+	Conn *scopedConn `wire:"auto"`
+}
+
+type scopedOptionalRepo struct {
+	Conn *scopedConn `wire:"auto,optional"`
+}
+
+type scopedHandler struct {
+	Conn *scopedConn `wire:"auto"`
+	Repo *scopedRepo `wire:"auto"`
+}
+
+func TestScoped(t *testing.T) {
+	c := NewContainer()
+	c.Scoped(new(scopedConn))
+	c.Scoped(new(scopedRepo))
+
+	ctx1, cancel1 := WithScope(context.Background())
+	ctx2, cancel2 := WithScope(context.Background())
+	defer cancel2()
+
+	var conn1, conn2, conn3 *scopedConn
+	c.FindContext(ctx1, &conn1)
+	c.FindContext(ctx1, &conn2)
+	c.FindContext(ctx2, &conn3)
+	assert.Same(t, conn1, conn2)
+	assert.NotSame(t, conn1, conn3)
+
+	var handler *scopedHandler
+	c.NewContext(ctx1, &handler)
+	assert.Same(t, conn1, handler.Conn)
+	assert.Same(t, conn1, handler.Repo.Conn)
+
+	cancel1()
+	assert.True(t, conn1.closed)
+	assert.False(t, conn3.closed)
+
+	assert.Panics(t, func() {
+		c.FindContext(ctx1, &conn1)
+	})
+}
+
+func TestScopedWithoutScope(t *testing.T) {
+	c := NewContainer()
+	c.Scoped(new(scopedConn))
+
+	var conn *scopedConn
+	assert.PanicsWithError(t, "scoped *factory.scopedConn must be got within a scope, use WithScope to create one", func() {
+		c.FindContext(context.Background(), &conn)
+	})
+	assert.ErrorIs(t, c.TryFind(&conn), ErrNoScope)
+
+	// registered, so an optional field isn't left nil silently
+	var repo *scopedOptionalRepo
+	assert.ErrorIs(t, c.TryNew(&repo), ErrNoScope)
+}
+
+type scopedService struct {
+	Conn Lazy[*scopedConn] `wire:"auto"`
+	All  []*scopedConn     `wire:"all"`
+}
+
+type scopedPrototype struct {
+	Conn *scopedConn `wire:"auto"`
+}
+
+func TestScopedCaptured(t *testing.T) {
+	c := NewContainer()
+	c.Scoped(new(scopedConn))
+	c.Singleton(new(scopedService))
+	c.Singleton(new(scopedRepo))
+	c.Prototype(new(scopedPrototype))
+
+	ctx, cancel := WithScope(context.Background())
+	defer cancel()
+
+	// the singletons and prototypes never keep the objects of the scope they are first got in
+	var service *scopedService
+	c.FindContext(ctx, &service)
+	assert.Empty(t, service.All)
+	assert.Panics(t, func() {
+		service.Conn.Get()
+	})
+
+	var repo *scopedRepo
+	assert.Panics(t, func() {
+		c.FindContext(ctx, &repo)
+	})
+
+	var prototype *scopedPrototype
+	assert.Panics(t, func() {
+		c.FindContext(ctx, &prototype)
+	})
+}
+
+type scopedSlow struct {
+	closed bool
+}
+
+var scopedSlowBuilding, scopedSlowRelease chan struct{}
+
+func (s *scopedSlow) Init() {
+	close(scopedSlowBuilding)
+	<-scopedSlowRelease
+}
+
+func (s *scopedSlow) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestScopedClosedWhileBuilding(t *testing.T) {
+	c := NewContainer()
+	c.Scoped(new(scopedSlow))
+
+	scopedSlowBuilding = make(chan struct{})
+	scopedSlowRelease = make(chan struct{})
+
+	ctx, cancel := WithScope(context.Background())
+
+	got := make(chan *scopedSlow)
+	go func() {
+		var slow *scopedSlow
+		c.FindContext(ctx, &slow)
+		got <- slow
+	}()
+
+	<-scopedSlowBuilding
+	cancel()
+	close(scopedSlowRelease)
+
+	assert.True(t, (<-got).closed)
+}
+
+type scopedRanged struct {
+	ranged bool
+}
+
+var _ = Scoped[scopedRanged]()
+
+func TestScopedRange(t *testing.T) {
+	assert.Empty(t, FindStructs[scopedRanged]())
+}
@@ -2,7 +2,6 @@ package factory
 
 import (
 	"context"
-	"fmt"
 	"github.com/expgo/sync"
 	"reflect"
 	"strings"
@@ -10,31 +9,38 @@ import (
 )
 
 type singleton struct {
-	once     sync.Once
+	once     *buildOnce
 	obj      any
 	initFunc func() any
 	option   Option
 
-	_name string
-	lock  sync.Mutex
+	_name   string
+	lock    sync.Mutex
+	context *factoryContext
+
+	registration *registration
 
 	cci *contextCachedItem
 }
 
-func _singletonWithType(vt reflect.Type) *singleton {
+func _singletonWithType(c *factoryContext, vt reflect.Type) *singleton {
 	result := &singleton{
-		once: sync.NewOnce(),
-		lock: sync.NewMutex(),
+		once:    newBuildOnce(),
+		lock:    sync.NewMutex(),
+		context: c,
 		option: Option{
 			lock: sync.NewMutex(),
 		},
 	}
 
-	result.cci = &contextCachedItem{}
+	result.cci = &contextCachedItem{context: c}
 
 	result.cci._type = vt
+	result.cci.option = &result.option
+	result.cci.kind = itemKindSingleton
 
 	result.obj = reflect.New(vt.Elem()).Interface()
+	result.registration = c.newRegistration(result.cci)
 
 	result.cci.getter = func(ctx context.Context) any {
 		return result.getWithContext(ctx)
@@ -44,15 +50,15 @@ func _singletonWithType(vt reflect.Type) *singleton {
 }
 
 func Singleton[T any]() *singleton {
-	return _singletonWithType(reflect.TypeOf((*T)(nil))).setType()
+	return _singletonWithType(_context, reflect.TypeOf((*T)(nil))).setType()
 }
 
 func NamedSingleton[T any](name string) *singleton {
-	return _singletonWithType(reflect.TypeOf((*T)(nil))).Name(name)
+	return _singletonWithType(_context, reflect.TypeOf((*T)(nil))).Name(name)
 }
 
 func (s *singleton) setType() *singleton {
-	_context.setByType(s.cci._type, s.cci)
+	s.registration.setType()
 	return s
 }
 
@@ -66,7 +72,7 @@ func (s *singleton) Name(name string) *singleton {
 	}
 
 	if len(s._name) == 0 {
-		_context.setByName(name, s.cci)
+		s.registration.setName(name)
 		s._name = name
 	} else {
 		panic("name already set")
@@ -80,12 +86,7 @@ func (s *singleton) WithOption(option *Option) *singleton {
 	defer s.lock.Unlock()
 
 	if option != nil {
-		s.option.lock.Lock()
-		defer s.option.lock.Unlock()
-
-		s.option.useConstructor = option.useConstructor
-		s.option.initMethodName = option.initMethodName
-		s.option.initParams = option.initParams
+		s.option.assign(option)
 	}
 
 	return s
@@ -96,6 +97,10 @@ func (s *singleton) SetInitFunc(initFunc func() any) *singleton {
 	defer s.lock.Unlock()
 
 	s.initFunc = initFunc
+	if initFunc != nil {
+		// the tags and init method aren't used when the object is built by initFunc
+		s.cci.option = nil
+	}
 	return s
 }
 
@@ -114,27 +119,101 @@ func (s *singleton) InitParams(initParams ...string) *singleton {
 	return s
 }
 
+// Lazy excludes the singleton from Start, it's only built when it's first got.
+func (s *singleton) Lazy(lazy bool) *singleton {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.cci.lazy = lazy
+	return s
+}
+
+// Profile makes the singleton only registered when one of profiles is active, such as a fake in "dev"
+// and the real adapter in "prod", see ActivateProfiles. It's called in the chain registering the singleton,
+// which isn't put into the builders before the chain is done.
+func (s *singleton) Profile(profiles ...string) *singleton {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.registration.setProfiles(profiles)
+	return s
+}
+
+// When makes the singleton only take effect when the expr code, such as "${env.CACHE == 'redis'}", is evaluated to true.
+func (s *singleton) When(code string) *singleton {
+	return s.If(When(code))
+}
+
+// If makes the singleton only take effect when condition holds, such as OnMissing[Cache]() or OnPresent[Metrics]().
+// The conditions are evaluated at Start, or when the singleton is looked up for the first time.
+func (s *singleton) If(condition Condition) *singleton {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.context.addCondition(s.cci, condition)
+	return s
+}
+
+// Primary marks the singleton as the one used when several registered types can be got by an interface type.
+// Name based lookups aren't affected.
+func (s *singleton) Primary() *singleton {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.cci.primary = true
+	return s
+}
+
+// Refreshable makes the fields with 'value' tags of the singleton re-evaluated when the config is reloaded,
+// see Container.ReloadConfig. They're set while the singleton may be in use, the singleton implementing
+// Lock and Unlock, such as by embedding a sync.RWMutex, is locked while they're set.
+func (s *singleton) Refreshable() *singleton {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.cci.refreshable = true
+	return s
+}
+
+// Order sets the position of the singleton in the slices injected by 'wire:"all"', the lower ones come first.
+func (s *singleton) Order(order int) *singleton {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.cci.order = order
+	return s
+}
+
+func (s *singleton) DestroyMethodName(destroyMethodName string) *singleton {
+	s.option.DestroyMethodName(destroyMethodName)
+	return s
+}
+
+// DestroyTimeout sets the time given to destroy the singleton, 0 uses Opts.DestroyTimeout.
+func (s *singleton) DestroyTimeout(destroyTimeout time.Duration) *singleton {
+	s.option.DestroyTimeout(destroyTimeout)
+	return s
+}
+
 func (s *singleton) getWithContext(ctx context.Context) any {
 	timeout := getContextTimeout(ctx)
-	err := s.once.DoTimeout(timeout, func() error {
+	s.once.do(timeout, func() {
 		if s.initFunc != nil {
 			s.obj = s.initFunc()
 		} else {
-			s.obj = initWithOptionContext(s.obj, getNextTimeoutContext(ctx), &s.option, nil)
+			s.obj = initWithOptionContext(s.obj, withContainer(withoutScope(getNextTimeoutContext(ctx)), s.context), &s.option, nil)
 		}
 
-		return nil
+		s.context.addCreated(s.cci, s.obj, &s.option)
+	}, func(err error) error {
+		return newResolveError(ErrTimeout, err, "[%s]init singleton %s, timeout: %s err: %+v", time.Now(), s.cci._type.String(), timeout, err)
 	})
 
-	if err != nil {
-		panic(fmt.Errorf("[%s]init singleton %s, timeout: %s err: %+v", time.Now(), s.cci._type.String(), timeout, err))
-	}
-
 	return s.obj
 }
 
 func Getter[T any](s *singleton) func() *T {
 	return func() *T {
-		return s.getWithContext(getTimeoutContext(Opts.Timeout)).(*T)
+		return s.getWithContext(s.context.timeoutContext(Opts.Timeout)).(*T)
 	}
 }
@@ -65,3 +65,39 @@ func TestSingletonBuilder(t *testing.T) {
 	assert.Equal(t, "cat1", c1().Name)
 	assert.Equal(t, "animal", c1().animal.Name)
 }
+
+type primaryConsumer struct {
+	Auto    tryStorage `wire:"auto"`
+	Typed   tryStorage `wire:"type"`
+	Memory  *tryMemory `wire:"name:memory"`
+	Storage *tryDisk   `wire:"name:disk"`
+}
+
+func TestSingletonPrimary(t *testing.T) {
+	c := NewContainer()
+	c.Singleton(new(tryDisk)).Name("disk").Primary()
+	c.Singleton(new(tryMemory)).Name("memory")
+	c.Singleton(new(primaryConsumer))
+
+	var consumer *primaryConsumer
+	c.Find(&consumer)
+
+	var disk *tryDisk
+	c.Find(&disk)
+	assert.Same(t, disk, consumer.Auto)
+	assert.Same(t, disk, consumer.Typed)
+	assert.Same(t, disk, consumer.Storage)
+	assert.NotNil(t, consumer.Memory)
+	assert.Nil(t, c.Validate())
+}
+
+func TestSingletonMultiplePrimary(t *testing.T) {
+	c := NewContainer()
+	c.Singleton(new(tryDisk)).Primary()
+	c.Singleton(new(tryMemory)).Primary()
+
+	var consumer *tryConsumer
+	assert.PanicsWithError(t, "Multiple primary builders found for type: factory.tryStorage", func() {
+		c.New(&consumer)
+	})
+}
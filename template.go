@@ -0,0 +1,233 @@
+package factory
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// templateSegment is a literal text or a '${}' expr of a value template.
+type templateSegment struct {
+	text   string
+	code   string
+	isExpr bool
+	// Spring style ${key:default}, the default is used when the key is missing
+	key          string
+	defaultValue string
+	hasDefault   bool
+}
+
+// springKeyPattern matches the Spring style ${key:default}, such as ${env.DB_PORT:5432} or ${db.port:5432}.
+var springKeyPattern = regexp.MustCompile(`^\s*([A-Za-z_][\w-]*(?:\.[\w-]+)*):(.*)$`)
+
+// parseTemplate splits value into literal texts and '${}' exprs, such as "postgres://${env.DB_HOST}:${env.DB_PORT ?? 5432}/app".
+// '$${' is an escaped '${'.
+func parseTemplate(value string) ([]templateSegment, error) {
+	var result []templateSegment
+	var text strings.Builder
+
+	for i := 0; i < len(value); {
+		if strings.HasPrefix(value[i:], "$${") {
+			text.WriteString("${")
+			i += 3
+			continue
+		}
+
+		if !strings.HasPrefix(value[i:], "${") {
+			text.WriteByte(value[i])
+			i++
+			continue
+		}
+
+		end, err := exprEnd(value, i+2)
+		if err != nil {
+			return nil, err
+		}
+
+		if text.Len() > 0 {
+			result = append(result, templateSegment{text: text.String()})
+			text.Reset()
+		}
+
+		segment := templateSegment{code: value[i+2 : end], isExpr: true}
+		if matches := springKeyPattern.FindStringSubmatch(segment.code); matches != nil {
+			segment.key, segment.defaultValue, segment.hasDefault = matches[1], matches[2], true
+		}
+		result = append(result, segment)
+
+		i = end + 1
+	}
+
+	if text.Len() > 0 {
+		result = append(result, templateSegment{text: text.String()})
+	}
+
+	return result, nil
+}
+
+// exprEnd returns the index of the '}' closing the expr starting at start, the braces and quotes inside the expr are skipped.
+func exprEnd(value string, start int) (int, error) {
+	depth := 0
+	var quote byte
+
+	for i := start; i < len(value); i++ {
+		ch := value[i]
+
+		if quote != 0 {
+			if ch == '\\' && quote != '`' {
+				i++
+			} else if ch == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		switch ch {
+		case '\'', '"', '`':
+			quote = ch
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				return i, nil
+			}
+			depth--
+		}
+	}
+
+	return 0, fmt.Errorf("unclosed '${' in %s", value)
+}
+
+// evalTemplate evaluates the segments with the per-object locals, a single expr keeps the type of its result,
+// otherwise the results are stringified and joined with the literal texts.
+func (c *factoryContext) evalTemplate(ctx context.Context, locals map[string]any, segments []templateSegment) (any, error) {
+	if len(segments) == 1 && segments[0].isExpr {
+		return c.evalSegment(ctx, locals, segments[0])
+	}
+
+	var sb strings.Builder
+	for _, segment := range segments {
+		if !segment.isExpr {
+			sb.WriteString(segment.text)
+			continue
+		}
+
+		value, err := c.evalSegment(ctx, locals, segment)
+		if err != nil {
+			return nil, err
+		}
+		if value != nil {
+			sb.WriteString(fmt.Sprint(value))
+		}
+	}
+
+	return sb.String(), nil
+}
+
+func (c *factoryContext) evalSegment(ctx context.Context, locals map[string]any, segment templateSegment) (any, error) {
+	if !segment.hasDefault {
+		return c.evalExpr(ctx, locals, segment.code)
+	}
+
+	path := strings.Split(segment.key, ".")
+	root := c.templateKeyRoot(ctx, locals, path[0])
+	if root != path[0] {
+		path = append([]string{root}, path...)
+	}
+
+	value, err := c.evalExpr(ctx, locals, root)
+	if err != nil {
+		return nil, err
+	}
+
+	if value, ok := walkKeyPath(value, path[1:]); ok {
+		return value, nil
+	}
+
+	return segment.defaultValue, nil
+}
+
+// templateKeyRoot returns the identifier a Spring style key starts from, a key which doesn't start with
+// a local, a registered expr var or a named builder, such as db.port, is read from the config.
+func (c *factoryContext) templateKeyRoot(ctx context.Context, locals map[string]any, name string) string {
+	if _, ok := locals[name]; ok {
+		return name
+	}
+
+	if _, ok := c.lookupExprVar(name); ok {
+		return name
+	}
+
+	if _, ok := c.getItemByName(ctx, name); ok {
+		return name
+	}
+
+	return "config"
+}
+
+// walkKeyPath gets the value of keys from the maps and structs inside value, a missing key or a nil value isn't found.
+func walkKeyPath(value any, keys []string) (any, bool) {
+	rv := reflect.ValueOf(value)
+
+	for _, key := range keys {
+		for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+			if rv.IsNil() {
+				return nil, false
+			}
+			rv = rv.Elem()
+		}
+
+		switch rv.Kind() {
+		case reflect.Map:
+			if rv.Type().Key().Kind() != reflect.String {
+				return nil, false
+			}
+			rv = rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key()))
+		case reflect.Struct:
+			rv = rv.FieldByName(key)
+		default:
+			return nil, false
+		}
+
+		if !rv.IsValid() {
+			return nil, false
+		}
+	}
+
+	if !rv.IsValid() || !rv.CanInterface() {
+		return nil, false
+	}
+
+	if (rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface || rv.Kind() == reflect.Map) && rv.IsNil() {
+		return nil, false
+	}
+
+	return rv.Interface(), true
+}
+
+// templateIdentifiers returns the identifiers used by the exprs of segments, locals are the names of the per-object identifiers.
+func (c *factoryContext) templateIdentifiers(ctx context.Context, locals map[string]any, segments []templateSegment) ([]string, error) {
+	var result []string
+
+	for _, segment := range segments {
+		if !segment.isExpr {
+			continue
+		}
+
+		if segment.hasDefault {
+			result = append(result, c.templateKeyRoot(ctx, locals, strings.Split(segment.key, ".")[0]))
+			continue
+		}
+
+		// compiled like it's evaluated, so the compile errors are found too
+		compiled, err := c.compileExpr(segment.code)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, compiled.identifiers...)
+	}
+
+	return result, nil
+}
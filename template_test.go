@@ -0,0 +1,53 @@
+package factory
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type templateDB struct {
+	URL     string `value:"postgres://${env.TPL_DB_USER}@${env.TPL_DB_HOST}:${env.TPL_DB_PORT ?? 5432}/app"`
+	Port    int    `value:"${env.TPL_DB_PORT:5432}"`
+	Pool    int    `value:"${db.pool:10}"`
+	Name    string `value:"${db.name:app}"`
+	Escaped string `value:"$${env.TPL_DB_USER} is ${env.TPL_DB_USER}"`
+	Literal string `value:"{'a': 1}"`
+	Map     int    `value:"${ {'a': 1, 'b': 2}.b }"`
+}
+
+func TestParseTemplate(t *testing.T) {
+	segments, err := parseTemplate("a${'}'}b$${c}${x:y}")
+	assert.Nil(t, err)
+	assert.Equal(t, []templateSegment{
+		{text: "a"},
+		{code: "'}'", isExpr: true},
+		{text: "b${c}"},
+		{code: "x:y", isExpr: true, key: "x", defaultValue: "y", hasDefault: true},
+	}, segments)
+
+	_, err = parseTemplate("a${b")
+	assert.EqualError(t, err, "unclosed '${' in a${b")
+}
+
+func TestTemplateValue(t *testing.T) {
+	t.Setenv("TPL_DB_USER", "admin")
+	t.Setenv("TPL_DB_HOST", "db.local")
+
+	c := NewContainer()
+	c.AddConfigSource(ConfigLayerDefault, MapSource(map[string]any{"db": map[string]any{"name": "orders"}}))
+	c.Singleton(new(templateDB))
+	assert.Nil(t, c.Validate())
+
+	var db *templateDB
+	c.Find(&db)
+
+	assert.Equal(t, &templateDB{
+		URL:     "postgres://admin@db.local:5432/app",
+		Port:    5432,
+		Pool:    10,
+		Name:    "orders",
+		Escaped: "${env.TPL_DB_USER} is admin",
+		Literal: "{'a': 1}",
+		Map:     2,
+	}, db)
+}
@@ -0,0 +1,76 @@
+package factory
+
+// TryFind is like Find, but returns an error instead of panicking,
+// the error can be matched with ErrNotFound, ErrAmbiguous, ErrCircular, ErrTimeout, ErrInitFailed, ErrNoScope
+// and ErrInvalidArgument.
+func TryFind[T any]() (result *T, err error) {
+	err = try(func() {
+		result = Find[T]()
+	})
+	return
+}
+
+// TryFindByName is like FindByName, but returns an error instead of panicking.
+func TryFindByName[T any](name string) (result *T, err error) {
+	err = try(func() {
+		result = FindByName[T](name)
+	})
+	return
+}
+
+// TryNew is like New, but returns an error instead of panicking.
+func TryNew[T any]() (result *T, err error) {
+	err = try(func() {
+		result = New[T]()
+	})
+	return
+}
+
+// TryNewWithOption is like NewWithOption, but returns an error instead of panicking.
+func TryNewWithOption[T any](option *Option) (result *T, err error) {
+	err = try(func() {
+		result = NewWithOption[T](option)
+	})
+	return
+}
+
+// TryAutoWire is like AutoWire, but the panics raised while wiring are returned as errors too.
+func TryAutoWire(self any) (err error) {
+	if e := try(func() {
+		err = AutoWire(self)
+	}); e != nil {
+		return e
+	}
+	return
+}
+
+// TryFind is like Find, but returns an error instead of panicking.
+func (c *Container) TryFind(ptr any) error {
+	return try(func() {
+		c.Find(ptr)
+	})
+}
+
+// TryFindByName is like FindByName, but returns an error instead of panicking.
+func (c *Container) TryFindByName(name string, ptr any) error {
+	return try(func() {
+		c.FindByName(name, ptr)
+	})
+}
+
+// TryNew is like New, but returns an error instead of panicking.
+func (c *Container) TryNew(ptr any) error {
+	return try(func() {
+		c.New(ptr)
+	})
+}
+
+// TryAutoWire is like AutoWire, but the panics raised while wiring are returned as errors too.
+func (c *Container) TryAutoWire(self any) (err error) {
+	if e := try(func() {
+		err = c.AutoWire(self)
+	}); e != nil {
+		return e
+	}
+	return
+}
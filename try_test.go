@@ -0,0 +1,105 @@
+package factory
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type tryMissing struct{}
+
+type tryStorage interface {
+	Store()
+}
+
+type tryDisk struct{}
+
+func (d *tryDisk) Store() {}
+
+type tryMemory struct{}
+
+func (m *tryMemory) Store() {}
+
+type tryConsumer struct {
+	Storage tryStorage `wire:"type"`
+}
+
+type tryBroken struct{}
+
+func (b *tryBroken) Init() {
+	panic("broken")
+}
+
+type trySlow struct{}
+
+// trySlowDone is closed when the Init of trySlow returns
+var trySlowDone chan struct{}
+
+func (s *trySlow) Init() {
+	defer close(trySlowDone)
+	time.Sleep(200 * time.Millisecond)
+}
+
+func TestTryFind(t *testing.T) {
+	_, err := TryFind[tryMissing]()
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.EqualError(t, err, "use type to get Getter, github.com/expgo/factory:tryMissing not found")
+
+	_, err = TryFindByName[tryMissing]("tryMissing")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	var re *ResolveError
+	assert.ErrorAs(t, err, &re)
+	assert.Equal(t, ErrNotFound, re.Kind)
+}
+
+func TestTryKinds(t *testing.T) {
+	c := NewContainer()
+	c.Singleton(new(tryDisk))
+	c.Singleton(new(tryMemory))
+	c.Singleton(new(tryBroken))
+	c.Singleton(new(type1))
+	c.Singleton(new(type2))
+
+	var consumer *tryConsumer
+	assert.ErrorIs(t, c.TryNew(&consumer), ErrAmbiguous)
+	assert.ErrorIs(t, c.TryAutoWire(&tryConsumer{}), ErrAmbiguous)
+
+	var broken *tryBroken
+	err := c.TryFind(&broken)
+	assert.ErrorIs(t, err, ErrInitFailed)
+	assert.EqualError(t, err, "broken")
+
+	var t1 *type1
+	assert.ErrorIs(t, c.TryFind(&t1), ErrCircular)
+
+	var missing *tryMissing
+	assert.ErrorIs(t, c.TryFindByName("missing", &missing), ErrNotFound)
+	assert.False(t, errors.Is(c.TryFindByName("missing", &missing), ErrInitFailed))
+
+	// not a pointer to a struct pointer
+	err = c.TryFind(missing)
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+	assert.False(t, errors.Is(err, ErrInitFailed))
+	assert.ErrorIs(t, c.TryFind((**tryMissing)(nil)), ErrInvalidArgument)
+	assert.ErrorIs(t, c.TryNew(nil), ErrInvalidArgument)
+}
+
+func TestTryTimeout(t *testing.T) {
+	enableTimeout, timeout := Opts.EnableTimeout, Opts.Timeout
+	Opts.EnableTimeout, Opts.Timeout = true, 150*time.Millisecond
+
+	trySlowDone = make(chan struct{})
+	defer func() {
+		// the timed out build still reads Opts, wait for it before restoring
+		<-trySlowDone
+		Opts.EnableTimeout, Opts.Timeout = enableTimeout, timeout
+	}()
+
+	c := NewContainer()
+	c.Singleton(new(trySlow))
+
+	var slow *trySlow
+	assert.ErrorIs(t, c.TryFind(&slow), ErrTimeout)
+}
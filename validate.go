@@ -0,0 +1,82 @@
+package factory
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Validate checks the registrations of the default container without building anything, see Container.Validate.
+func Validate() error {
+	return _context.validate()
+}
+
+func (c *factoryContext) validate() error {
+	var errs []string
+
+	g, _ := c.staticGraph(func(node string, ref *staticRef, err error) {
+		if ref != nil && len(ref.field) > 0 {
+			node = fmt.Sprintf("%s.%s", node, ref.field)
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", node, err))
+	})
+
+	for _, cycle := range findCycles(g) {
+		errs = append(errs, fmt.Sprintf("%s: circular reference: %s", cycle[0], strings.Join(cycle, " -> ")))
+	}
+
+	if len(errs) > 0 {
+		return &Error{Errors: errs}
+	}
+
+	return nil
+}
+
+// findCycles returns the cycles of g, every cycle is listed once, and ends with the node it starts from.
+func findCycles(g *DependencyGraph) (cycles [][]string) {
+	deps := make(map[string][]string)
+	for _, edge := range g.Edges {
+		// lazy dependencies are got after building, so they can't make a cycle
+		if edge.Source != "lazy" {
+			deps[edge.From] = append(deps[edge.From], edge.To)
+		}
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	states := make(map[string]int)
+	var path []string
+
+	var visit func(node string)
+	visit = func(node string) {
+		states[node] = visiting
+		path = append(path, node)
+
+		for _, dep := range deps[node] {
+			switch states[dep] {
+			case visiting:
+				for i := len(path) - 1; i >= 0; i-- {
+					if path[i] == dep {
+						cycle := append(append([]string{}, path[i:]...), dep)
+						cycles = append(cycles, cycle)
+						break
+					}
+				}
+			case 0:
+				visit(dep)
+			}
+		}
+
+		path = path[:len(path)-1]
+		states[node] = visited
+	}
+
+	for _, node := range g.Nodes {
+		if states[node.ID] == 0 {
+			visit(node.ID)
+		}
+	}
+
+	return cycles
+}
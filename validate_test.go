@@ -0,0 +1,90 @@
+package factory
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type validRepo struct{}
+
+type validService struct {
+	Repo   *validRepo `wire:"auto"`
+	Home   string     `value:"${env.HOME}"`
+	Helper graphStore `new:""`
+}
+
+func (s *validService) Init(repo *validRepo) {}
+
+type invalidService struct {
+	Missing *tryMissing `wire:"type"`
+	Named   *validRepo  `wire:"name:repo"`
+	Storage tryStorage  `wire:"type"`
+	Count   int         `wire:"auto"`
+	Expr    string      `value:"${env.}"`
+	Typed   string      `value:"${1 + 'a'}"`
+	Helper  graphStore  `new:""`
+}
+
+func (s *invalidService) Start(a *validRepo) {}
+
+type invalidInterface interface {
+	Run()
+}
+
+type invalidInit struct{}
+
+func (i *invalidInit) Init(repo *validRepo) error {
+	return nil
+}
+
+type validCycle1 struct {
+	Next *validCycle2 `wire:"auto"`
+}
+
+type validCycle2 struct {
+	Next *validCycle1 `wire:"type"`
+}
+
+func TestValidate(t *testing.T) {
+	c := NewContainer()
+	c.Singleton(new(validRepo))
+	c.Singleton(new(validService))
+	c.Factory(new(graphStore), newGraphStore)
+
+	assert.Nil(t, c.Validate())
+
+	var service *validService
+	c.Find(&service)
+	assert.NotNil(t, service.Repo)
+}
+
+func TestValidateErrors(t *testing.T) {
+	c := NewContainer()
+	c.Singleton(new(validRepo))
+	c.Singleton(new(tryDisk))
+	c.Singleton(new(tryMemory))
+	c.Singleton(new(invalidService)).InitMethodName("Start").InitParams("type", "type", "type")
+	c.Singleton(new(invalidInit))
+	c.Interface((*invalidInterface)(nil))
+	c.Singleton(new(validCycle1))
+	c.Singleton(new(validCycle2))
+
+	err := c.Validate()
+
+	var e *Error
+	assert.True(t, errors.As(err, &e))
+	assert.ElementsMatch(t, []string{
+		"*factory.invalidService.Missing: *factory.tryMissing not found",
+		"*factory.invalidService.Named: Named builder repo not found.",
+		"*factory.invalidService.Storage: Multiple default builders found for type: factory.tryStorage, please use named singleton",
+		"*factory.invalidService.Count: ‘self’, ’auto’, ’type’ and ’name’ tag only used on *struct or interface",
+		"*factory.invalidService.Expr: tag value value:${env.} expr compile err: unexpected end of expression (1:4)\n | env.\n | ...^",
+		"*factory.invalidService.Typed: tag value value:${1 + 'a'} expr compile err: invalid operation: + (mismatched types int and string) (1:3)\n | 1 + 'a'\n | ..^",
+		"factory.invalidInterface: initFunc must be set",
+		"*factory.invalidService.Helper: can't get factory type of factory.graphStore",
+		"*factory.invalidService.Start: init params count must equals with method params count",
+		"*factory.invalidInit.Init: init method 'Init' must not have return values",
+		"*factory.validCycle1: circular reference: *factory.validCycle1 -> *factory.validCycle2 -> *factory.validCycle1",
+	}, e.Errors)
+}
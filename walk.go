@@ -0,0 +1,91 @@
+package factory
+
+import (
+	"errors"
+	"github.com/expgo/structure"
+	"reflect"
+)
+
+// walkTags is like structure.WalkWithTagNames, but the struct fields converted from strings, such as time.Time,
+// aren't walked into, so walkFn is called for them like the other fields.
+func walkTags(v any, tagNames []string, walkFn structure.ParamsWalkFunc[map[string]string]) error {
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Ptr {
+		return errors.New("result must be a pointer")
+	}
+
+	val = val.Elem()
+	if !val.CanAddr() {
+		return errors.New("result must be addressable (a pointer)")
+	}
+
+	if val.Kind() != reflect.Struct {
+		return errors.New("result must be a struct")
+	}
+
+	return walkTagsValue(val, tagNames, walkFn, nil, true)
+}
+
+// walkOwnTags is like walkTags, but the objects referred by pointers, such as the wired dependencies, aren't walked into.
+func walkOwnTags(v any, tagNames []string, walkFn structure.ParamsWalkFunc[map[string]string]) error {
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Struct {
+		return errors.New("result must be a pointer to a struct")
+	}
+
+	return walkTagsValue(val.Elem(), tagNames, walkFn, nil, false)
+}
+
+func walkTagsValue(val reflect.Value, tagNames []string, walkFn structure.ParamsWalkFunc[map[string]string], rootValues []reflect.Value, intoPointers bool) error {
+	rootValues = append(rootValues, val)
+	valType := val.Type()
+
+	for i := 0; i < valType.NumField(); i++ {
+		fieldValue := val.Field(i)
+		structField := valType.Field(i)
+
+		ff := fieldValue
+		if reflect.Ptr == fieldValue.Kind() && !fieldValue.IsNil() && intoPointers {
+			ff = fieldValue.Elem()
+		}
+
+		if reflect.Struct == ff.Kind() && !isConvertible(structField.Type) {
+			if err := walkTagsValue(ff, tagNames, walkFn, rootValues, intoPointers); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if reflect.Slice == ff.Kind() {
+			elemType := ff.Type().Elem()
+			for j := 0; j < ff.Len(); j++ {
+				elem := ff.Index(j)
+				if reflect.Ptr == elemType.Kind() && reflect.Struct == elemType.Elem().Kind() && elem.CanAddr() && !elem.IsNil() && elem.CanInterface() && intoPointers {
+					if err := walkTagsValue(elem.Elem(), tagNames, walkFn, rootValues, intoPointers); err != nil {
+						return err
+					}
+				}
+				if reflect.Struct == elemType.Kind() && elem.CanAddr() && elem.CanInterface() {
+					if err := walkTagsValue(elem, tagNames, walkFn, rootValues, intoPointers); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		tags := map[string]string{}
+		for _, tagName := range tagNames {
+			if tagValue, ok := structField.Tag.Lookup(tagName); ok {
+				tags[tagName] = tagValue
+			}
+		}
+
+		if len(tags) > 0 {
+			if err := walkFn(fieldValue, structField, rootValues, tags); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}